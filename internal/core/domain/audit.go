@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// AuditAction is a privileged action recorded in the audit log
+type AuditAction string
+
+const (
+	AuditDeleteTweet   AuditAction = "delete_tweet"
+	AuditSuspendUser   AuditAction = "suspend_user"
+	AuditUnsuspendUser AuditAction = "unsuspend_user"
+	AuditGrantRole     AuditAction = "grant_role"
+)
+
+// AuditEntry records that ActorId took Action against TargetUserId, and
+// TweetId for tweet deletions. ActorId is 0 for the system itself. Detail
+// holds what the action changed, Reason why the actor took it.
+type AuditEntry struct {
+	Id           int
+	ActorId      int
+	Action       AuditAction
+	TargetUserId int
+	TweetId      int
+	Detail       string
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// AuditPage is one page of the audit log, NextCursor is empty on the last page
+type AuditPage struct {
+	Entries    []AuditEntry
+	NextCursor string
+}
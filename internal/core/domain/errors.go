@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds, every error the core hands out wraps exactly one of these so
+// adapters can tell them apart with errors.Is
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrForbidden       = errors.New("forbidden")
+	ErrValidation      = errors.New("validation failed")
+	ErrUnauthenticated = errors.New("unauthenticated")
+)
+
+// Specific errors returned by the use cases and repositories
+var (
+	ErrUserNotFound       = fmt.Errorf("user %w", ErrNotFound)
+	ErrTweetNotFound      = fmt.Errorf("tweet %w", ErrNotFound)
+	ErrEmailTaken         = fmt.Errorf("%w: email already registered", ErrConflict)
+	ErrHandleTaken        = fmt.Errorf("%w: handle already taken", ErrConflict)
+	ErrInvalidHandle      = fmt.Errorf("%w: handles are 3 to 15 letters, digits or underscores and not only digits", ErrValidation)
+	ErrProfileTooLong     = fmt.Errorf("%w: profile field too long", ErrValidation)
+	ErrNotTweetAuthor     = fmt.Errorf("%w: not the author of this tweet", ErrForbidden)
+	ErrTweetTooLong       = fmt.Errorf("%w: tweet too long", ErrValidation)
+	ErrInvalidCredentials = fmt.Errorf("%w: invalid credentials", ErrUnauthenticated)
+	ErrSelfFollow         = fmt.Errorf("%w: users can't follow themselves", ErrValidation)
+	ErrAlreadyFollowing   = fmt.Errorf("%w: already following this user", ErrConflict)
+	ErrNotFollowing       = fmt.Errorf("follow %w", ErrNotFound)
+	ErrAlreadyLiked       = fmt.Errorf("%w: already liked this tweet", ErrConflict)
+	ErrNotLiked           = fmt.Errorf("like %w", ErrNotFound)
+	ErrAlreadyRetweeted   = fmt.Errorf("%w: already retweeted this tweet", ErrConflict)
+	ErrRetweetWithBody    = fmt.Errorf("%w: retweets can't have a body", ErrValidation)
+	ErrMissingReference   = fmt.Errorf("%w: retweets and quotes need a referenced tweet", ErrValidation)
+	ErrInvalidTweetKind   = fmt.Errorf("%w: unknown tweet kind", ErrValidation)
+	ErrRetweetReply       = fmt.Errorf("%w: retweets can't be replies", ErrValidation)
+	ErrTokenNotFound      = fmt.Errorf("token %w", ErrNotFound)
+	ErrSessionNotFound    = fmt.Errorf("session %w", ErrNotFound)
+	ErrTokenExists        = fmt.Errorf("%w: token already stored", ErrConflict)
+	ErrTokenRotated       = fmt.Errorf("%w: token already rotated or revoked", ErrConflict)
+	ErrTokenUsed          = fmt.Errorf("%w: token already used", ErrConflict)
+	ErrTokenExpired       = fmt.Errorf("%w: token expired", ErrConflict)
+	ErrInvalidScope       = fmt.Errorf("%w: unknown scope", ErrValidation)
+	ErrInvalidRole        = fmt.Errorf("%w: unknown role", ErrValidation)
+	ErrAccountSuspended   = fmt.Errorf("%w: account suspended", ErrForbidden)
+	ErrNotPermitted       = fmt.Errorf("%w: not permitted", ErrForbidden)
+)
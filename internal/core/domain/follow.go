@@ -0,0 +1,7 @@
+package domain
+
+// FollowStats counts both sides of a user's place in the follow graph
+type FollowStats struct {
+	Followers int
+	Following int
+}
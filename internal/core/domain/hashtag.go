@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// HashtagUse is one tweet tagged with a hashtag
+type HashtagUse struct {
+	Tag       string
+	CreatedAt time.Time
+}
+
+// Trend is a hashtag that is being used a lot right now
+type Trend struct {
+	Tag string
+	// Score weighs every use by its age, recent uses count the most
+	Score float64
+	// Uses is how many tweets used the tag within the trend window
+	Uses int
+}
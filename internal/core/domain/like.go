@@ -0,0 +1,8 @@
+package domain
+
+// LikeStats is the engagement on a tweet as seen by one viewer
+type LikeStats struct {
+	Count int
+	// Liked is whether the viewer likes the tweet
+	Liked bool
+}
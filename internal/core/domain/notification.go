@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// NotificationKind is the event a notification tells its recipient about
+type NotificationKind string
+
+const (
+	NotificationMention NotificationKind = "mention"
+	NotificationLike    NotificationKind = "like"
+	NotificationFollow  NotificationKind = "follow"
+	NotificationReply   NotificationKind = "reply"
+)
+
+// Notification tells UserId that ActorId did something, TweetId is the tweet
+// it happened on and 0 for follows
+type Notification struct {
+	Id        int
+	UserId    int
+	Kind      NotificationKind
+	ActorId   int
+	TweetId   int
+	Read      bool
+	CreatedAt time.Time
+}
+
+// NotificationPage is one page of notifications, NextCursor is empty on the last page
+type NotificationPage struct {
+	Notifications []Notification
+	NextCursor    string
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// PageRequest is a client's request for one page of an ordered listing. The
+// cursor is opaque to clients, it comes from the NextCursor of the previous page.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// TweetFilter narrows down and orders a tweet listing
+type TweetFilter struct {
+	// AuthorId restricts the listing to one author, 0 means every author
+	AuthorId   int
+	Descending bool
+	// Since and Until bound CreatedAt to [Since, Until), zero means unbounded
+	Since time.Time
+	Until time.Time
+}
+
+// Matches reports whether the tweet falls inside the filter
+func (f TweetFilter) Matches(tweet Tweet) bool {
+	if f.AuthorId != 0 && tweet.AuthorId != f.AuthorId {
+		return false
+	}
+
+	if !f.Since.IsZero() && tweet.CreatedAt.Before(f.Since) {
+		return false
+	}
+
+	return f.Until.IsZero() || tweet.CreatedAt.Before(f.Until)
+}
+
+// TweetPage is one page of tweets, NextCursor is empty on the last page
+type TweetPage struct {
+	Tweets     []Tweet
+	NextCursor string
+}
+
+// UserPage is one page of users, NextCursor is empty on the last page
+type UserPage struct {
+	Users      []User
+	NextCursor string
+}
@@ -0,0 +1,21 @@
+package domain
+
+// SearchOrder is how tweet search results are ranked
+type SearchOrder string
+
+const (
+	SearchByRelevance SearchOrder = "relevance"
+	SearchByRecency   SearchOrder = "recent"
+)
+
+// SearchQuery is a parsed tweet search. Matching is case-insensitive and works
+// on whole words.
+type SearchQuery struct {
+	// Terms must all appear in a tweet, in any order
+	Terms []string
+	// Phrases must each appear in a tweet word for word
+	Phrases []string
+	// AuthorId restricts the search to one author, 0 means every author
+	AuthorId int
+	Order    SearchOrder
+}
@@ -0,0 +1,18 @@
+package domain
+
+// ThreadNode is a tweet of a conversation with the first page of its replies
+type ThreadNode struct {
+	Tweet      Tweet
+	ReplyCount int
+	Replies    []ThreadNode
+	// NextCursor continues Replies through the thread of Tweet
+	NextCursor string
+}
+
+// Thread is a tweet in the context of its conversation
+type Thread struct {
+	// Ancestors run from the conversation root down to the parent of the
+	// tweet, stopping early where a tweet in between was deleted
+	Ancestors []Tweet
+	ThreadNode
+}
@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+// RefreshToken is a stored refresh token. Every refresh swaps the token for
+// a new one of the same family, so a family stands for one login session.
+// Rotated is set once a token has been swapped, presenting it again is reuse.
+type RefreshToken struct {
+	Token    string
+	UserId   int
+	FamilyId string
+	Rotated  bool
+	Revoked  bool
+	// Client is who asked for the token, LoggedInAt when its family started
+	Client
+	LoggedInAt time.Time
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Client is where a request came from
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one login of a user, the family of its refresh tokens. It was
+// last used when its latest refresh token was issued, by Client.
+type Session struct {
+	Id string
+	Client
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// AccessTokenPrefix starts the secret of every personal access token, which
+// tells them apart from the JWTs handed out at login
+const AccessTokenPrefix = "chirpy_pat_"
+
+// Scope is something a personal access token allows its holder to do
+type Scope string
+
+const (
+	ScopeTweetsRead  Scope = "tweets:read"
+	ScopeTweetsWrite Scope = "tweets:write"
+	// ScopeProfileWrite covers the public profile, never the email or password
+	ScopeProfileWrite       Scope = "profile:write"
+	ScopeFollowsWrite       Scope = "follows:write"
+	ScopeNotificationsRead  Scope = "notifications:read"
+	ScopeNotificationsWrite Scope = "notifications:write"
+)
+
+// Scopes lists every scope a personal access token can be granted
+var Scopes = []Scope{
+	ScopeTweetsRead,
+	ScopeTweetsWrite,
+	ScopeProfileWrite,
+	ScopeFollowsWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+}
+
+// AccessToken is a named, long-lived personal access token that lets a user's
+// scripts act on their behalf within its scopes. Only the SHA-256 hash of its
+// secret is stored, the secret is shown once when the token is created.
+type AccessToken struct {
+	Id        string
+	UserId    int
+	Name      string
+	Scopes    []Scope
+	Hash      string
+	CreatedAt time.Time
+}
+
+// HasScope tells whether the token was granted the scope
+func (t AccessToken) HasScope(scope Scope) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PasswordReset lets the owner of an email address set a new password for
+// its account once, before it expires. Only the SHA-256 hash of its secret is
+// stored, the secret itself is emailed.
+type PasswordReset struct {
+	Hash      string
+	UserId    int
+	Used      bool
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -1,7 +1,46 @@
 package domain
 
+import "time"
+
+// TweetKind tells original tweets apart from reposts of other tweets
+type TweetKind string
+
+const (
+	TweetKindOriginal TweetKind = "original"
+	// a retweet reposts another tweet as is and has no body of its own
+	TweetKindRetweet TweetKind = "retweet"
+	// a quote reposts another tweet with a comment
+	TweetKindQuote TweetKind = "quote"
+)
+
+// Tweet is a post. Retweets and quotes point at the tweet they repost
+// through ReferencedTweetId, which is 0 for originals. Replies point at their
+// parent through InReplyTo, and every tweet of a thread shares the id of its
+// root as ConversationId. Hashtags are lower cased and unique.
 type Tweet struct {
-	TweetId  int    `json:"id"`
-	Body     string `json:"body"`
-	AuthorId int    `json:"author_id"`
+	TweetId           int       `json:"id"`
+	Body              string    `json:"body"`
+	AuthorId          int       `json:"author_id"`
+	Kind              TweetKind `json:"kind"`
+	ReferencedTweetId int       `json:"referenced_tweet_id,omitempty"`
+	InReplyTo         int       `json:"in_reply_to,omitempty"`
+	ConversationId    int       `json:"conversation_id"`
+	Hashtags          []string  `json:"hashtags,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TweetDraft is what an author sends to post a tweet
+type TweetDraft struct {
+	Body              string
+	Kind              TweetKind
+	ReferencedTweetId int
+	InReplyTo         int
+}
+
+// RetweetStats is how often a tweet was retweeted as seen by one viewer
+type RetweetStats struct {
+	Count int
+	// Retweeted is whether the viewer retweeted the tweet
+	Retweeted bool
 }
@@ -1,8 +1,70 @@
 package domain
 
+import "time"
+
 type User struct {
 	Email          string
 	HashedPassword []byte
 	ID             int
 	IsChirpyRed    bool
+	Profile
+	Role Role
+	// Suspended users can't log in or act with the tokens they hold
+	Suspended bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Role is what a user may do beyond their own account and tweets
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleModerator deletes any tweet and suspends users
+	RoleModerator Role = "moderator"
+	// RoleAdmin does everything a moderator does, grants roles and reads the audit log
+	RoleAdmin Role = "admin"
+)
+
+// Valid tells whether r is one of the known roles
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// Outranks tells whether r sits above other, users are at the bottom
+func (r Role) Outranks(other Role) bool {
+	return r.rank() > other.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleAdmin:
+		return 2
+	case RoleModerator:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// UserUpdate changes some of a user's account fields, nil ones are left as
+// they are. Changing the email or the password takes the current password.
+type UserUpdate struct {
+	Email           *string
+	Password        *string
+	CurrentPassword string
+}
+
+// Profile is the public part of a user. Handle is unique ignoring case and
+// keeps the casing the user chose; it is empty until the user picks one.
+type Profile struct {
+	Handle      string
+	DisplayName string
+	Bio         string
+	Location    string
 }
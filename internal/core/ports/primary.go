@@ -1,20 +1,93 @@
 package ports
 
-import "github.com/anandh86/chirpy/internal/core/domain"
+import (
+	"context"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
 
 // IUseCase is a primary port that the core must respond to
 type IUseCase interface {
-	CreateUser(emailid string, password string) (domain.User, error)
-	UpdateUser(id int, emailid string, password string) error
-	UpdateUserMembership(id int, isMember bool) error
-	GetUserById(id int) (domain.User, error)
-	LoginUser(emailid string, password string) (int, error)
-	PostTweet(body string, author_id int) (domain.Tweet, error)
-	DeleteTweet(tweetId int, author_id int) error
-	GetTweetById(id int) (domain.Tweet, error)
-	GetAllTweets() ([]domain.Tweet, error)
-	GetAuthorTweets(author_id int) ([]domain.Tweet, error)
-	StoreRefreshToken(token string) bool
-	RevokeRefreshToken(token string) bool
-	IsRefreshTokenRevoked(token string) bool
+	// CreateUser registers a user, the profile is optional and can be filled in later
+	CreateUser(ctx context.Context, emailid string, password string, profile domain.Profile) (domain.User, error)
+	// UpdateUser applies a partial update to the user's account and returns the
+	// updated user. A password change logs out every session but sessionId.
+	UpdateUser(ctx context.Context, id int, sessionId string, update domain.UserUpdate) (domain.User, error)
+	UpdateUserMembership(ctx context.Context, id int, isMember bool) error
+	GetUserById(ctx context.Context, id int) (domain.User, error)
+	// GetUserByHandle looks a user up by handle, ignoring case and a leading @
+	GetUserByHandle(ctx context.Context, handle string) (domain.User, error)
+	// UpdateProfile replaces the user's whole profile
+	UpdateProfile(ctx context.Context, userId int, profile domain.Profile) error
+	LoginUser(ctx context.Context, emailid string, password string) (int, error)
+	// PostTweet posts an original tweet, a retweet or a quote, optionally as a reply
+	PostTweet(ctx context.Context, draft domain.TweetDraft, author_id int) (domain.Tweet, error)
+	// DeleteTweet deletes the actor's own tweet, RemoveTweet anyone's
+	DeleteTweet(ctx context.Context, tweetId int, actorId int) error
+	GetTweetById(ctx context.Context, id int) (domain.Tweet, error)
+	ListTweets(ctx context.Context, filter domain.TweetFilter, page domain.PageRequest) (domain.TweetPage, error)
+	// SearchTweets runs a search query, see usecases.parseSearchQuery for its syntax
+	SearchTweets(ctx context.Context, query string, order domain.SearchOrder, page domain.PageRequest) (domain.TweetPage, error)
+	ListHashtagTweets(ctx context.Context, tag string, page domain.PageRequest) (domain.TweetPage, error)
+	// GetTrends returns up to limit of the hashtags trending right now, hottest first
+	GetTrends(ctx context.Context, limit int) ([]domain.Trend, error)
+	// GetThread returns a tweet with its ancestors and a page of its replies
+	GetThread(ctx context.Context, tweetId int, page domain.PageRequest) (domain.Thread, error)
+	FollowUser(ctx context.Context, followerId int, followeeId int) error
+	UnfollowUser(ctx context.Context, followerId int, followeeId int) error
+	ListFollowers(ctx context.Context, userId int, page domain.PageRequest) (domain.UserPage, error)
+	ListFollowing(ctx context.Context, userId int, page domain.PageRequest) (domain.UserPage, error)
+	GetFollowStats(ctx context.Context, userId int) (domain.FollowStats, error)
+	LikeTweet(ctx context.Context, userId int, tweetId int) error
+	UnlikeTweet(ctx context.Context, userId int, tweetId int) error
+	ListTweetLikers(ctx context.Context, tweetId int, page domain.PageRequest) (domain.UserPage, error)
+	ListUserLikes(ctx context.Context, userId int, page domain.PageRequest) (domain.TweetPage, error)
+	// GetLikeStats returns the stats of every given tweet as seen by viewerId (0 for anonymous viewers)
+	GetLikeStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.LikeStats, error)
+	GetRetweetStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.RetweetStats, error)
+	HomeTimeline(ctx context.Context, userId int, page domain.PageRequest) (domain.TweetPage, error)
+	ListNotifications(ctx context.Context, userId int, unreadOnly bool, page domain.PageRequest) (domain.NotificationPage, error)
+	// MarkNotificationsRead marks the given notifications read, all of them when ids is empty
+	MarkNotificationsRead(ctx context.Context, userId int, ids []int) error
+	CountUnreadNotifications(ctx context.Context, userId int) (int, error)
+	// IssueRefreshToken stores the refresh token of a fresh login as the first
+	// of a new family and returns the id of the session it starts
+	IssueRefreshToken(ctx context.Context, userId int, token string, expiresAt time.Time, client domain.Client) (string, error)
+	// RotateRefreshToken swaps a refresh token for next and returns its session
+	// id. Presenting a token that was already swapped revokes its whole family.
+	RotateRefreshToken(ctx context.Context, token string, next string, expiresAt time.Time, client domain.Client) (string, error)
+	// RevokeRefreshToken revokes the family of the token, logging its login out
+	RevokeRefreshToken(ctx context.Context, token string) error
+	ListSessions(ctx context.Context, userId int) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userId int, sessionId string) error
+	// CheckSession fails with domain.ErrUnauthenticated unless the user can
+	// still refresh the session, it was neither logged out nor has it expired
+	CheckSession(ctx context.Context, userId int, sessionId string) error
+	// RevokeSessions revokes every session of the user but keepSessionId, all of them when it is empty
+	RevokeSessions(ctx context.Context, userId int, keepSessionId string) error
+	// CreateAccessToken mints a personal access token and returns it along
+	// with its secret, which is not stored and can't be shown again
+	CreateAccessToken(ctx context.Context, userId int, name string, scopes []domain.Scope) (domain.AccessToken, string, error)
+	ListAccessTokens(ctx context.Context, userId int) ([]domain.AccessToken, error)
+	RevokeAccessToken(ctx context.Context, userId int, id string) error
+	// AuthenticateAccessToken returns the personal access token the secret belongs to
+	AuthenticateAccessToken(ctx context.Context, secret string) (domain.AccessToken, error)
+	// RequestPasswordReset emails a password reset token to the address when
+	// it belongs to a user. It succeeds either way, so the answer doesn't
+	// tell who has an account.
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword sets a new password with a token from RequestPasswordReset
+	// and logs the user out of every session
+	ResetPassword(ctx context.Context, token string, password string) error
+	// GetActiveUser fails with domain.ErrAccountSuspended for suspended users
+	GetActiveUser(ctx context.Context, userId int) (domain.User, error)
+	// RemoveTweet, SuspendUser, UnsuspendUser, GrantRole and ListAuditLog are
+	// privileged, the policy decides whether the actor may take them. Every
+	// change they make is recorded in the audit log along with the reason.
+	RemoveTweet(ctx context.Context, actorId int, tweetId int, reason string) error
+	SuspendUser(ctx context.Context, actorId int, userId int, reason string) (domain.User, error)
+	UnsuspendUser(ctx context.Context, actorId int, userId int, reason string) (domain.User, error)
+	GrantRole(ctx context.Context, actorId int, userId int, role domain.Role, reason string) (domain.User, error)
+	ListAuditLog(ctx context.Context, actorId int, page domain.PageRequest) (domain.AuditPage, error)
 }
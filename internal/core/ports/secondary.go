@@ -1,20 +1,156 @@
 package ports
 
-import "github.com/anandh86/chirpy/internal/core/domain"
+import (
+	"context"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
 
 // IRepository is a secondary port that the core will make calls to
 type IRepository interface {
-	Save(user domain.User) (domain.User, error)
-	GetUserById(id int) (domain.User, error)
-	GetUserId(emailid string) (int, error)
-	UpdateUser(id int, user domain.User) error
-	UpdateUserMembership(id int, isMember bool) error
-	SaveTweet(tweet domain.Tweet) (domain.Tweet, error)
-	DeleteTweet(tweet domain.Tweet) error
-	GetTweetById(id int) (domain.Tweet, error)
-	FetchAllTweets() ([]domain.Tweet, error)
-	FetchAuthorTweets(author_id int) ([]domain.Tweet, error)
-	CreateToken(token string) bool
-	ReadToken(token string) bool
-	UpdateToken(token string, revokeStatus bool) bool
+	// Save stores users without a role as domain.RoleUser
+	Save(ctx context.Context, user domain.User) (domain.User, error)
+	GetUserById(ctx context.Context, id int) (domain.User, error)
+	GetUserId(ctx context.Context, emailid string) (int, error)
+	// GetUserIdByHandle looks a user up by handle, ignoring case
+	GetUserIdByHandle(ctx context.Context, handle string) (int, error)
+	// UpdateUser sets the email and the password hash, an empty one keeps the
+	// stored value, and touches nothing else. UpdateProfile replaces only the
+	// profile. They fail with domain.ErrEmailTaken or domain.ErrHandleTaken
+	// when the new value belongs to another user.
+	UpdateUser(ctx context.Context, id int, user domain.User) error
+	UpdateProfile(ctx context.Context, id int, user domain.User) error
+	UpdateUserMembership(ctx context.Context, id int, isMember bool) error
+	UpdateUserRole(ctx context.Context, id int, role domain.Role) error
+	UpdateUserSuspension(ctx context.Context, id int, suspended bool) error
+	// SaveTweet stores tweets without a kind as originals and fails with
+	// domain.ErrAlreadyRetweeted when the author already retweeted the referenced
+	// tweet. A tweet without a ConversationId starts a conversation of its own.
+	SaveTweet(ctx context.Context, tweet domain.Tweet) (domain.Tweet, error)
+	// DeleteTweet removes the tweet along with its likes and retweets.
+	// Quotes of it are kept and keep referencing it.
+	DeleteTweet(ctx context.Context, tweet domain.Tweet) error
+	GetTweetById(ctx context.Context, id int) (domain.Tweet, error)
+	// ListTweets returns up to limit tweets ordered by id, starting right
+	// after afterId in the requested direction (0 starts from the beginning)
+	ListTweets(ctx context.Context, filter domain.TweetFilter, afterId int, limit int) ([]domain.Tweet, error)
+	// ListReplies returns up to limit direct replies to a tweet in ascending
+	// id order, starting right after afterId
+	ListReplies(ctx context.Context, tweetId int, afterId int, limit int) ([]domain.Tweet, error)
+	CountReplies(ctx context.Context, tweetIds []int) (map[int]int, error)
+	// ListTweetsByHashtag returns up to limit tweets tagged with the hashtag
+	// newest first, starting right before beforeId
+	ListTweetsByHashtag(ctx context.Context, tag string, beforeId int, limit int) ([]domain.Tweet, error)
+	// HashtagUses returns every use of a hashtag by tweets created since then
+	HashtagUses(ctx context.Context, since time.Time) ([]domain.HashtagUse, error)
+	Follow(ctx context.Context, followerId int, followeeId int) error
+	Unfollow(ctx context.Context, followerId int, followeeId int) error
+	// ListFollowers and ListFollowing return up to limit user ids in ascending
+	// order, starting right after afterId (0 starts from the beginning)
+	ListFollowers(ctx context.Context, userId int, afterId int, limit int) ([]int, error)
+	ListFollowing(ctx context.Context, userId int, afterId int, limit int) ([]int, error)
+	CountFollows(ctx context.Context, userId int) (domain.FollowStats, error)
+	// LikeTweet and UnlikeTweet fail with domain.ErrTweetNotFound for unknown tweets
+	LikeTweet(ctx context.Context, userId int, tweetId int) error
+	UnlikeTweet(ctx context.Context, userId int, tweetId int) error
+	// ListTweetLikers returns up to limit user ids in ascending order, starting right after afterId
+	ListTweetLikers(ctx context.Context, tweetId int, afterId int, limit int) ([]int, error)
+	// ListUserLikes returns up to limit liked tweet ids newest first, starting right before beforeId
+	ListUserLikes(ctx context.Context, userId int, beforeId int, limit int) ([]int, error)
+	LikeStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.LikeStats, error)
+	RetweetStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.RetweetStats, error)
+	// SaveRefreshToken fails with domain.ErrTokenExists for a token already stored
+	SaveRefreshToken(ctx context.Context, token domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, token string) (domain.RefreshToken, error)
+	// RotateRefreshToken marks a token rotated and stores its successor in one
+	// step. It fails with domain.ErrTokenRotated, leaving everything as it
+	// was, when the token is already rotated or revoked.
+	RotateRefreshToken(ctx context.Context, token string, next domain.RefreshToken) error
+	RevokeTokenFamily(ctx context.Context, familyId string) error
+	// ListRefreshTokens returns the latest token of every unrevoked family of
+	// the user, which may have expired
+	ListRefreshTokens(ctx context.Context, userId int) ([]domain.RefreshToken, error)
+	// GetLatestRefreshToken returns the latest token of the family, which may
+	// have expired. It fails with domain.ErrTokenNotFound once the family is revoked.
+	GetLatestRefreshToken(ctx context.Context, familyId string) (domain.RefreshToken, error)
+	// SaveAccessToken fails with domain.ErrTokenExists when the id or the hash is already stored
+	SaveAccessToken(ctx context.Context, token domain.AccessToken) error
+	GetAccessTokenByHash(ctx context.Context, hash string) (domain.AccessToken, error)
+	// ListAccessTokens returns the user's personal access tokens, oldest first
+	ListAccessTokens(ctx context.Context, userId int) ([]domain.AccessToken, error)
+	// DeleteAccessToken fails with domain.ErrTokenNotFound unless the user owns the token
+	DeleteAccessToken(ctx context.Context, userId int, id string) error
+	// SavePasswordReset fails with domain.ErrTokenExists when the hash is already stored
+	SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error
+	// UsePasswordReset marks a password reset used along with every other
+	// reset of its user and returns it as it was before. It fails with
+	// domain.ErrTokenUsed when it was used already and with
+	// domain.ErrTokenExpired when it expired by now, leaving everything as it was.
+	UsePasswordReset(ctx context.Context, hash string, now time.Time) (domain.PasswordReset, error)
+	// SaveAuditEntry appends to the audit log, assigning the entry an id
+	SaveAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error)
+	// ListAuditEntries returns up to limit entries newest first, starting right before beforeId
+	ListAuditEntries(ctx context.Context, beforeId int, limit int) ([]domain.AuditEntry, error)
+	// SaveNotification, ListNotifications, MarkNotificationsRead and
+	// CountUnreadNotifications keep notifications the way INotificationStore
+	// describes its Add, List, MarkRead and CountUnread
+	SaveNotification(ctx context.Context, notification domain.Notification) (domain.Notification, error)
+	ListNotifications(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error)
+	MarkNotificationsRead(ctx context.Context, userId int, ids []int) error
+	CountUnreadNotifications(ctx context.Context, userId int) (int, error)
+}
+
+// ITimelineCache is a secondary port holding materialized home timelines as
+// ascending tweet ids per user. Every user's timeline has a version that
+// moves with each Push and Invalidate, so a timeline rebuilt from the
+// repository isn't installed over changes made while it was being built.
+type ITimelineCache interface {
+	// Get returns a copy of the user's timeline, its version and whether one
+	// is materialized. The version is returned either way.
+	Get(ctx context.Context, userId int) ([]int, uint64, bool)
+	// Set materializes the user's timeline unless its version moved on from
+	// version, and tells whether it did
+	Set(ctx context.Context, userId int, tweetIds []int, version uint64) bool
+	// Push appends a tweet to a materialized timeline, dropping the oldest
+	// entries beyond maxLen. Users without a timeline only get a new version.
+	Push(ctx context.Context, userId int, tweetId int, maxLen int)
+	Invalidate(ctx context.Context, userId int)
+}
+
+// ITweetSearchIndex is a secondary port for full-text search over tweets
+type ITweetSearchIndex interface {
+	// Index adds a tweet to the index, replacing an earlier version of it
+	Index(ctx context.Context, tweet domain.Tweet) error
+	Remove(ctx context.Context, tweetId int) error
+	// Search returns up to limit ids of matching tweets in the order the query
+	// asks for. The results start right after the key of the previous page,
+	// which is the last tweet id returned for recency order and the number of
+	// results returned so far for relevance order (0 starts from the top).
+	Search(ctx context.Context, query domain.SearchQuery, afterKey int, limit int) ([]int, error)
+}
+
+// INotificationStore is a secondary port keeping every user's notifications
+type INotificationStore interface {
+	// Add stores a notification and assigns its id. When the user already has
+	// a notification of the same kind from the same actor about the same
+	// tweet, Add returns that one instead, so liking a tweet over and over
+	// notifies its author once.
+	Add(ctx context.Context, notification domain.Notification) (domain.Notification, error)
+	// List returns up to limit of the user's notifications newest first,
+	// starting right before beforeId (0 starts from the newest)
+	List(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error)
+	// MarkRead marks the given notifications of the user read, all of them when ids is empty
+	MarkRead(ctx context.Context, userId int, ids []int) error
+	CountUnread(ctx context.Context, userId int) (int, error)
+}
+
+// IMailer is a secondary port delivering email
+type IMailer interface {
+	Send(ctx context.Context, email domain.Email) error
+}
+
+// IClock is a secondary port telling the core what time it is
+type IClock interface {
+	Now() time.Time
 }
@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// Limits on personal access tokens
+const (
+	maxAccessTokenNameLength = 50
+	maxAccessTokensPerUser   = 50
+)
+
+var (
+	errInvalidAccessToken  = fmt.Errorf("%w: invalid personal access token", domain.ErrUnauthenticated)
+	errAccessTokenName     = fmt.Errorf("%w: personal access tokens need a name of at most %d characters", domain.ErrValidation, maxAccessTokenNameLength)
+	errNoScopes            = fmt.Errorf("%w: personal access tokens need at least one scope", domain.ErrValidation)
+	errTooManyAccessTokens = fmt.Errorf("%w: at most %d personal access tokens per user", domain.ErrValidation, maxAccessTokensPerUser)
+)
+
+func (u userUseCase) CreateAccessToken(ctx context.Context, userId int, name string, scopes []domain.Scope) (domain.AccessToken, string, error) {
+
+	name = strings.TrimSpace(name)
+
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenNameLength {
+		return domain.AccessToken{}, "", errAccessTokenName
+	}
+
+	scopes, err := normalizeScopes(scopes)
+
+	if err != nil {
+		return domain.AccessToken{}, "", err
+	}
+
+	existing, err := u.repoImpl.ListAccessTokens(ctx, userId)
+
+	if err != nil {
+		return domain.AccessToken{}, "", err
+	}
+
+	if len(existing) >= maxAccessTokensPerUser {
+		return domain.AccessToken{}, "", errTooManyAccessTokens
+	}
+
+	id, err := randomHex(16)
+
+	if err != nil {
+		return domain.AccessToken{}, "", err
+	}
+
+	secret, err := randomHex(32)
+
+	if err != nil {
+		return domain.AccessToken{}, "", err
+	}
+
+	secret = domain.AccessTokenPrefix + secret
+
+	token := domain.AccessToken{
+		Id:        id,
+		UserId:    userId,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(secret),
+		CreatedAt: u.clock.Now(),
+	}
+
+	if err := u.repoImpl.SaveAccessToken(ctx, token); err != nil {
+		return domain.AccessToken{}, "", err
+	}
+
+	return token, secret, nil
+}
+
+func (u userUseCase) ListAccessTokens(ctx context.Context, userId int) ([]domain.AccessToken, error) {
+	return u.repoImpl.ListAccessTokens(ctx, userId)
+}
+
+func (u userUseCase) RevokeAccessToken(ctx context.Context, userId int, id string) error {
+	return u.repoImpl.DeleteAccessToken(ctx, userId, id)
+}
+
+func (u userUseCase) AuthenticateAccessToken(ctx context.Context, secret string) (domain.AccessToken, error) {
+
+	if !strings.HasPrefix(secret, domain.AccessTokenPrefix) {
+		return domain.AccessToken{}, errInvalidAccessToken
+	}
+
+	token, err := u.repoImpl.GetAccessTokenByHash(ctx, hashSecret(secret))
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return token, errInvalidAccessToken
+	}
+
+	return token, err
+}
+
+// normalizeScopes checks every scope is known and sorts them without repeats
+func normalizeScopes(scopes []domain.Scope) ([]domain.Scope, error) {
+
+	granted := make(map[domain.Scope]bool, len(scopes))
+
+	for _, scope := range scopes {
+		known := false
+
+		for _, candidate := range domain.Scopes {
+			known = known || candidate == scope
+		}
+
+		if !known {
+			return nil, fmt.Errorf("%w %q", domain.ErrInvalidScope, scope)
+		}
+
+		granted[scope] = true
+	}
+
+	if len(granted) == 0 {
+		return nil, errNoScopes
+	}
+
+	normalized := make([]domain.Scope, 0, len(granted))
+
+	for scope := range granted {
+		normalized = append(normalized, scope)
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i] < normalized[j]
+	})
+
+	return normalized, nil
+}
+
+// hashSecret hashes a secret for storage. The secrets are random and
+// long enough that a fast hash is as good as a slow one, and a fast hash can
+// be looked up directly.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,256 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// maxAuditReasonLength bounds the reason recorded with an admin action
+const maxAuditReasonLength = 500
+
+var (
+	errSelfModeration = fmt.Errorf("%w: users can't moderate themselves", domain.ErrForbidden)
+	errReasonTooLong  = fmt.Errorf("%w: reasons are at most %d characters", domain.ErrValidation, maxAuditReasonLength)
+)
+
+func (u userUseCase) RemoveTweet(ctx context.Context, actorId int, tweetId int, reason string) error {
+
+	if _, err := u.authorize(ctx, actorId, permDeleteAnyTweet); err != nil {
+		return err
+	}
+
+	tweet, err := u.repoImpl.GetTweetById(ctx, tweetId)
+
+	if err != nil {
+		return err
+	}
+
+	return u.removeTweet(ctx, actorId, tweet, reason)
+}
+
+// removeTweet deletes someone's tweet on behalf of a moderator and records it
+func (u userUseCase) removeTweet(ctx context.Context, actorId int, tweet domain.Tweet, reason string) error {
+
+	reason, err := normalizeReason(reason)
+
+	if err != nil {
+		return err
+	}
+
+	if err := u.deleteTweet(ctx, tweet); err != nil {
+		return err
+	}
+
+	return u.audit(ctx, domain.AuditEntry{
+		ActorId:      actorId,
+		Action:       domain.AuditDeleteTweet,
+		TargetUserId: tweet.AuthorId,
+		TweetId:      tweet.TweetId,
+		Detail:       tweet.Body,
+		Reason:       reason,
+	})
+}
+
+func (u userUseCase) SuspendUser(ctx context.Context, actorId int, userId int, reason string) (domain.User, error) {
+	return u.setSuspended(ctx, actorId, userId, true, reason)
+}
+
+func (u userUseCase) UnsuspendUser(ctx context.Context, actorId int, userId int, reason string) (domain.User, error) {
+	return u.setSuspended(ctx, actorId, userId, false, reason)
+}
+
+// setSuspended suspends or reinstates a user. Suspending also logs the user
+// out everywhere. Asking for the state the user is already in changes nothing.
+func (u userUseCase) setSuspended(ctx context.Context, actorId int, userId int, suspended bool, reason string) (domain.User, error) {
+
+	reason, err := normalizeReason(reason)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	_, target, err := u.authorizeOver(ctx, actorId, userId, permSuspendUsers)
+
+	if err != nil || target.Suspended == suspended {
+		return target, err
+	}
+
+	if err := u.repoImpl.UpdateUserSuspension(ctx, userId, suspended); err != nil {
+		return domain.User{}, err
+	}
+
+	action := domain.AuditUnsuspendUser
+
+	if suspended {
+		action = domain.AuditSuspendUser
+
+		if err := u.RevokeSessions(ctx, userId, ""); err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	err = u.audit(ctx, domain.AuditEntry{ActorId: actorId, Action: action, TargetUserId: userId, Reason: reason})
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return u.repoImpl.GetUserById(ctx, userId)
+}
+
+func (u userUseCase) GrantRole(ctx context.Context, actorId int, userId int, role domain.Role, reason string) (domain.User, error) {
+
+	if !role.Valid() {
+		return domain.User{}, domain.ErrInvalidRole
+	}
+
+	reason, err := normalizeReason(reason)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	actor, target, err := u.authorizeOver(ctx, actorId, userId, permGrantRoles)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// nobody hands out their own rank or a higher one, admins are only
+	// appointed from the configuration
+	if !actor.Role.Outranks(role) {
+		return domain.User{}, domain.ErrNotPermitted
+	}
+
+	if target.Role == role {
+		return target, nil
+	}
+
+	if err := u.repoImpl.UpdateUserRole(ctx, userId, role); err != nil {
+		return domain.User{}, err
+	}
+
+	err = u.audit(ctx, domain.AuditEntry{
+		ActorId:      actorId,
+		Action:       domain.AuditGrantRole,
+		TargetUserId: userId,
+		Detail:       fmt.Sprintf("%s to %s", target.Role, role),
+		Reason:       reason,
+	})
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return u.repoImpl.GetUserById(ctx, userId)
+}
+
+func (u userUseCase) ListAuditLog(ctx context.Context, actorId int, page domain.PageRequest) (domain.AuditPage, error) {
+
+	if _, err := u.authorize(ctx, actorId, permReadAuditLog); err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	beforeId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	// fetch one extra entry to find out whether there is a next page
+	entries, err := u.repoImpl.ListAuditEntries(ctx, beforeId, limit+1)
+
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	result := domain.AuditPage{Entries: entries}
+
+	if len(entries) > limit {
+		result.Entries = entries[:limit]
+		result.NextCursor = encodeCursor(entries[limit-1].Id)
+	}
+
+	return result, nil
+}
+
+// GetActiveUser returns the user unless they are suspended
+func (u userUseCase) GetActiveUser(ctx context.Context, userId int) (domain.User, error) {
+
+	user, err := u.repoImpl.GetUserById(ctx, userId)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if user.Suspended {
+		return domain.User{}, domain.ErrAccountSuspended
+	}
+
+	return user, nil
+}
+
+// audit records a privileged action. The action has already been taken, a
+// failure to record it is reported to the actor all the same.
+func (u userUseCase) audit(ctx context.Context, entry domain.AuditEntry) error {
+	entry.CreatedAt = u.clock.Now()
+
+	if _, err := u.repoImpl.SaveAuditEntry(ctx, entry); err != nil {
+		log.Printf("Couldn't record %s by user %d in the audit log: %s", entry.Action, entry.ActorId, err)
+		return err
+	}
+
+	return nil
+}
+
+func normalizeReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+
+	if len([]rune(reason)) > maxAuditReasonLength {
+		return "", errReasonTooLong
+	}
+
+	return reason, nil
+}
+
+// PromoteAdmin makes the user registered with the email an admin, recording
+// it in the audit log as done by the system. It is the only way admins are
+// appointed, from ADMIN_EMAIL, since GrantRole never hands out the role.
+func PromoteAdmin(ctx context.Context, repo ports.IRepository, clock ports.IClock, email string) error {
+	userId, err := repo.GetUserId(ctx, email)
+
+	if err != nil {
+		return err
+	}
+
+	user, err := repo.GetUserById(ctx, userId)
+
+	if err != nil || user.Role == domain.RoleAdmin {
+		return err
+	}
+
+	if err := repo.UpdateUserRole(ctx, userId, domain.RoleAdmin); err != nil {
+		return err
+	}
+
+	_, err = repo.SaveAuditEntry(ctx, domain.AuditEntry{
+		Action:       domain.AuditGrantRole,
+		TargetUserId: userId,
+		Detail:       fmt.Sprintf("%s to %s", user.Role, domain.RoleAdmin),
+		Reason:       "appointed from the configuration",
+		CreatedAt:    clock.Now(),
+	})
+
+	return err
+}
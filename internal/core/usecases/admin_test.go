@@ -0,0 +1,171 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// staff makes a user of each role, and a suspended admin
+func staff(t *testing.T, env testEnv) map[string]domain.User {
+	t.Helper()
+
+	ctx := context.Background()
+	roles := map[string]domain.Role{
+		"user":           domain.RoleUser,
+		"otherUser":      domain.RoleUser,
+		"moderator":      domain.RoleModerator,
+		"otherModerator": domain.RoleModerator,
+		"admin":          domain.RoleAdmin,
+		"otherAdmin":     domain.RoleAdmin,
+		"suspendedAdmin": domain.RoleAdmin,
+	}
+	users := make(map[string]domain.User, len(roles))
+
+	for name, role := range roles {
+		user := mustUser(t, env.repo, name)
+
+		if err := env.repo.UpdateUserRole(ctx, user.ID, role); err != nil {
+			t.Fatal(err)
+		}
+
+		users[name], _ = env.repo.GetUserById(ctx, user.ID)
+	}
+
+	if err := env.repo.UpdateUserSuspension(ctx, users["suspendedAdmin"].ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	return users
+}
+
+func TestAuthorizeOver(t *testing.T) {
+	tests := []struct {
+		actor  string
+		target string
+		action permission
+		// wantErr is the error expected, nil when the actor may go ahead
+		wantErr error
+	}{
+		{"moderator", "user", permSuspendUsers, nil},
+		{"moderator", "otherModerator", permSuspendUsers, domain.ErrNotPermitted},
+		{"moderator", "admin", permSuspendUsers, domain.ErrNotPermitted},
+		{"moderator", "moderator", permSuspendUsers, errSelfModeration},
+		{"moderator", "user", permGrantRoles, domain.ErrNotPermitted},
+		{"admin", "moderator", permSuspendUsers, nil},
+		{"admin", "otherAdmin", permSuspendUsers, domain.ErrNotPermitted},
+		{"admin", "admin", permGrantRoles, errSelfModeration},
+		{"user", "otherUser", permSuspendUsers, domain.ErrNotPermitted},
+		{"user", "user", permSuspendUsers, domain.ErrNotPermitted},
+		{"suspendedAdmin", "user", permSuspendUsers, domain.ErrNotPermitted},
+	}
+
+	env := newTestEnv()
+	users := staff(t, env)
+
+	for _, tt := range tests {
+		t.Run(tt.actor+"Over"+tt.target, func(t *testing.T) {
+			actor, target, err := env.uc.authorizeOver(context.Background(), users[tt.actor].ID, users[tt.target].ID, tt.action)
+
+			if err != tt.wantErr {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && (actor.ID != users[tt.actor].ID || target.ID != users[tt.target].ID) {
+				t.Fatalf("expected actor %d over %d, got %d over %d", users[tt.actor].ID, users[tt.target].ID, actor.ID, target.ID)
+			}
+		})
+	}
+}
+
+func TestGrantRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  string
+		target string
+		role   domain.Role
+		// wantErr is the error kind expected, nil for success
+		wantErr error
+	}{
+		{"AdminAppointsModerator", "admin", "user", domain.RoleModerator, nil},
+		{"AdminDemotesModerator", "admin", "moderator", domain.RoleUser, nil},
+		{"AdminCantAppointAdmin", "admin", "user", domain.RoleAdmin, domain.ErrNotPermitted},
+		{"AdminCantDemoteAdmin", "admin", "otherAdmin", domain.RoleUser, domain.ErrNotPermitted},
+		{"AdminCantDemoteThemselves", "admin", "admin", domain.RoleUser, errSelfModeration},
+		{"ModeratorCantGrant", "moderator", "user", domain.RoleModerator, domain.ErrNotPermitted},
+		{"UnknownRole", "admin", "user", domain.Role("owner"), domain.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			users := staff(t, env)
+			target := users[tt.target]
+
+			_, err := env.uc.GrantRole(ctx, users[tt.actor].ID, target.ID, tt.role, "")
+
+			if err != tt.wantErr {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			wantRole := target.Role
+			if tt.wantErr == nil {
+				wantRole = tt.role
+			}
+
+			if got, _ := env.repo.GetUserById(ctx, target.ID); got.Role != wantRole {
+				t.Fatalf("expected the role to be %s, got %s", wantRole, got.Role)
+			}
+		})
+	}
+}
+
+func TestSuspendedUserLockedOut(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+	moderator := staff(t, env)["moderator"]
+	user, err := env.uc.CreateUser(ctx, "suspended@example.com", "secret", domain.Profile{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionId, err := env.uc.IssueRefreshToken(ctx, user.ID, "refresh", env.clock.Now().Add(time.Hour), domain.Client{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.uc.SuspendUser(ctx, moderator.ID, user.ID, "spam"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.uc.LoginUser(ctx, user.Email, "secret"); !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Fatalf("expected the login to be refused, got %v", err)
+	}
+
+	// a wrong password doesn't tell the account is suspended
+	if _, err := env.uc.LoginUser(ctx, user.Email, "guess"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	if _, err := env.uc.GetActiveUser(ctx, user.ID); !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Fatalf("expected the user to be inactive, got %v", err)
+	}
+
+	if err := env.uc.CheckSession(ctx, user.ID, sessionId); err == nil {
+		t.Fatal("the suspension left a session open")
+	}
+
+	if _, err := env.uc.UnsuspendUser(ctx, moderator.ID, user.ID, "appeal"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.uc.LoginUser(ctx, user.Email, "secret"); err != nil {
+		t.Fatalf("expected the reinstated user to log in, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a mention is an @ followed by a handle, not preceded by anything that
+// would make it part of a word or an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,30})`)
+
+// a hashtag is a # followed by a word with at least one letter in it, so
+// "#1" is not a hashtag
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\w#&])#(\w*[A-Za-z_]\w*)`)
+
+// maxHashtagLength bounds how long a hashtag can be
+const maxHashtagLength = 50
+
+// extractMentions returns the handles mentioned in a tweet body, lower cased
+// and in order of first appearance
+func extractMentions(body string) []string {
+	return extractEntities(mentionPattern, body)
+}
+
+// extractHashtags returns the hashtags used in a tweet body without the #,
+// lower cased and in order of first appearance
+func extractHashtags(body string) []string {
+	tags := make([]string, 0)
+
+	for _, tag := range extractEntities(hashtagPattern, body) {
+		if len(tag) <= maxHashtagLength {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// normalizeHashtag turns user input like "#Golang" into the form hashtags are stored in
+func normalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func extractEntities(pattern *regexp.Regexp, body string) []string {
+	entities := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+		entity := strings.ToLower(match[1])
+
+		if !seen[entity] {
+			seen[entity] = true
+			entities = append(entities, entity)
+		}
+	}
+
+	return entities
+}
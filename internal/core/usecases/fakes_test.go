@@ -0,0 +1,105 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+	adapters "github.com/anandh86/chirpy/internal/repositories"
+)
+
+// fakeRepository is the in-memory repository with a hook that runs after
+// every ListTweets, to interleave other work with a use case
+type fakeRepository struct {
+	ports.IRepository
+	afterListTweets func(filter domain.TweetFilter)
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{IRepository: adapters.ProvideInMemoryRepo()}
+}
+
+func (r *fakeRepository) ListTweets(ctx context.Context, filter domain.TweetFilter, afterId int, limit int) ([]domain.Tweet, error) {
+	tweets, err := r.IRepository.ListTweets(ctx, filter, afterId, limit)
+
+	if r.afterListTweets != nil {
+		r.afterListTweets(filter)
+	}
+
+	return tweets, err
+}
+
+// testEnv is a use case wired to fakes, which the test drives directly
+type testEnv struct {
+	uc     userUseCase
+	repo   *fakeRepository
+	clock  *fakeClock
+	mailer *fakeMailer
+}
+
+func newTestEnv() testEnv {
+	repo := newFakeRepository()
+	clock := newFakeClock()
+	mailer := &fakeMailer{}
+
+	return testEnv{
+		uc: userUseCase{
+			repoImpl:      repo,
+			timeline:      ProvideFanOutOnReadTimeline(repo),
+			clock:         clock,
+			notifications: adapters.ProvideInMemoryNotificationStore(),
+			search:        adapters.ProvideInMemorySearchIndex(),
+			mailer:        mailer,
+		},
+		repo:   repo,
+		clock:  clock,
+		mailer: mailer,
+	}
+}
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// fakeMailer keeps the emails it was asked to send
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []domain.Email
+}
+
+func (m *fakeMailer) Send(ctx context.Context, email domain.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func (m *fakeMailer) Sent() []domain.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]domain.Email(nil), m.sent...)
+}
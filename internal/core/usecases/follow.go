@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func (u userUseCase) FollowUser(ctx context.Context, followerId int, followeeId int) error {
+
+	if followerId == followeeId {
+		return domain.ErrSelfFollow
+	}
+
+	if _, err := u.repoImpl.GetUserById(ctx, followeeId); err != nil {
+		return err
+	}
+
+	if err := u.repoImpl.Follow(ctx, followerId, followeeId); err != nil {
+		return err
+	}
+
+	u.notify(ctx, domain.Notification{UserId: followeeId, Kind: domain.NotificationFollow, ActorId: followerId})
+
+	return u.timeline.FollowChanged(ctx, followerId, followeeId)
+}
+
+func (u userUseCase) UnfollowUser(ctx context.Context, followerId int, followeeId int) error {
+
+	if err := u.repoImpl.Unfollow(ctx, followerId, followeeId); err != nil {
+		return err
+	}
+
+	return u.timeline.FollowChanged(ctx, followerId, followeeId)
+}
+
+func (u userUseCase) ListFollowers(ctx context.Context, userId int, page domain.PageRequest) (domain.UserPage, error) {
+
+	if _, err := u.repoImpl.GetUserById(ctx, userId); err != nil {
+		return domain.UserPage{}, err
+	}
+
+	return u.pageUsers(ctx, page, func(ctx context.Context, afterId int, limit int) ([]int, error) {
+		return u.repoImpl.ListFollowers(ctx, userId, afterId, limit)
+	})
+}
+
+func (u userUseCase) ListFollowing(ctx context.Context, userId int, page domain.PageRequest) (domain.UserPage, error) {
+
+	if _, err := u.repoImpl.GetUserById(ctx, userId); err != nil {
+		return domain.UserPage{}, err
+	}
+
+	return u.pageUsers(ctx, page, func(ctx context.Context, afterId int, limit int) ([]int, error) {
+		return u.repoImpl.ListFollowing(ctx, userId, afterId, limit)
+	})
+}
+
+func (u userUseCase) GetFollowStats(ctx context.Context, userId int) (domain.FollowStats, error) {
+
+	if _, err := u.repoImpl.GetUserById(ctx, userId); err != nil {
+		return domain.FollowStats{}, err
+	}
+
+	return u.repoImpl.CountFollows(ctx, userId)
+}
+
+// pageUsers turns a listing of ascending user ids into a page of users
+func (u userUseCase) pageUsers(
+	ctx context.Context,
+	page domain.PageRequest,
+	list func(ctx context.Context, afterId int, limit int) ([]int, error),
+) (domain.UserPage, error) {
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	afterId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	// fetch one extra id to find out whether there is a next page
+	ids, err := list(ctx, afterId, limit+1)
+
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	result := domain.UserPage{Users: make([]domain.User, 0, len(ids))}
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+		result.NextCursor = encodeCursor(ids[limit-1])
+	}
+
+	for _, id := range ids {
+		user, err := u.repoImpl.GetUserById(ctx, id)
+
+		if err != nil {
+			return domain.UserPage{}, err
+		}
+
+		result.Users = append(result.Users, user)
+	}
+
+	return result, nil
+}
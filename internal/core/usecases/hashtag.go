@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+const (
+	// trendWindow is how far back trends look
+	trendWindow = 24 * time.Hour
+	// trendHalfLife is the age at which a use counts half as much as a fresh one
+	trendHalfLife = 2 * time.Hour
+
+	defaultTrends = 10
+	maxTrends     = 50
+)
+
+func (u userUseCase) ListHashtagTweets(ctx context.Context, tag string, page domain.PageRequest) (domain.TweetPage, error) {
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	beforeId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	// fetch one extra tweet to find out whether there is a next page
+	tweets, err := u.repoImpl.ListTweetsByHashtag(ctx, normalizeHashtag(tag), beforeId, limit+1)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	result := domain.TweetPage{Tweets: tweets}
+
+	if len(tweets) > limit {
+		result.Tweets = tweets[:limit]
+		result.NextCursor = encodeCursor(tweets[limit-1].TweetId)
+	}
+
+	return result, nil
+}
+
+// GetTrends scores every hashtag used within the trend window by its uses,
+// each decayed by its age, so a burst of recent uses beats a steady trickle
+func (u userUseCase) GetTrends(ctx context.Context, limit int) ([]domain.Trend, error) {
+
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("%w: limit must be positive", domain.ErrValidation)
+	case limit == 0:
+		limit = defaultTrends
+	case limit > maxTrends:
+		limit = maxTrends
+	}
+
+	now := u.clock.Now()
+
+	uses, err := u.repoImpl.HashtagUses(ctx, now.Add(-trendWindow))
+
+	if err != nil {
+		return nil, err
+	}
+
+	trends := make(map[string]*domain.Trend)
+
+	for _, use := range uses {
+		trend, ok := trends[use.Tag]
+
+		if !ok {
+			trend = &domain.Trend{Tag: use.Tag}
+			trends[use.Tag] = trend
+		}
+
+		age := max(now.Sub(use.CreatedAt), 0)
+		trend.Score += math.Exp2(-float64(age) / float64(trendHalfLife))
+		trend.Uses++
+	}
+
+	result := make([]domain.Trend, 0, len(trends))
+
+	for _, trend := range trends {
+		result = append(result, *trend)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Tag < result[j].Tag
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package usecases
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// hashtagUse is a tweet using tag posted age before the trends are asked for
+type hashtagUse struct {
+	tag string
+	age time.Duration
+}
+
+func TestGetTrendsDecay(t *testing.T) {
+	tests := []struct {
+		name string
+		uses []hashtagUse
+		want []domain.Trend
+	}{
+		{"FreshUseCountsOne", []hashtagUse{{"go", 0}}, []domain.Trend{{Tag: "go", Score: 1, Uses: 1}}},
+		{"HalfLifeHalves", []hashtagUse{{"go", 2 * time.Hour}}, []domain.Trend{{Tag: "go", Score: 0.5, Uses: 1}}},
+		{"TwoHalfLivesQuarter", []hashtagUse{{"go", 4 * time.Hour}}, []domain.Trend{{Tag: "go", Score: 0.25, Uses: 1}}},
+		{"UsesAddUp", []hashtagUse{{"go", 0}, {"go", 2 * time.Hour}}, []domain.Trend{{Tag: "go", Score: 1.5, Uses: 2}}},
+		// a clock running ahead doesn't make a use count more than a fresh one
+		{"FutureUseCountsOne", []hashtagUse{{"go", -time.Hour}}, []domain.Trend{{Tag: "go", Score: 1, Uses: 1}}},
+		{"WindowEdge", []hashtagUse{{"go", 24 * time.Hour}}, []domain.Trend{{Tag: "go", Score: 1.0 / 4096, Uses: 1}}},
+		{"OutsideWindow", []hashtagUse{{"go", 24*time.Hour + time.Second}}, []domain.Trend{}},
+		{"BurstBeatsTrickle", []hashtagUse{
+			{"trickle", 4 * time.Hour}, {"trickle", 6 * time.Hour}, {"trickle", 8 * time.Hour}, {"trickle", 10 * time.Hour},
+			{"burst", 0}, {"burst", 0},
+		}, []domain.Trend{{Tag: "burst", Score: 2, Uses: 2}, {Tag: "trickle", Score: 0.46875, Uses: 4}}},
+		{"OldBurstLosesToFreshUse", []hashtagUse{
+			{"old", 6 * time.Hour}, {"old", 6 * time.Hour}, {"old", 6 * time.Hour}, {"old", 6 * time.Hour},
+			{"fresh", 0},
+		}, []domain.Trend{{Tag: "fresh", Score: 1, Uses: 1}, {Tag: "old", Score: 0.5, Uses: 4}}},
+		{"TiesByTag", []hashtagUse{{"b", time.Hour}, {"a", time.Hour}}, []domain.Trend{{Tag: "a", Score: math.Sqrt2 / 2, Uses: 1}, {Tag: "b", Score: math.Sqrt2 / 2, Uses: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			author := mustUser(t, env.repo, "author")
+
+			// tweets are saved in the order they were posted, oldest first
+			uses := append([]hashtagUse(nil), tt.uses...)
+			sort.SliceStable(uses, func(i, j int) bool { return uses[i].age > uses[j].age })
+
+			for _, use := range uses {
+				tweet := domain.Tweet{Body: "#" + use.tag, AuthorId: author.ID, Hashtags: []string{use.tag}, CreatedAt: env.clock.Now().Add(-use.age)}
+
+				if _, err := env.repo.SaveTweet(ctx, tweet); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			trends, err := env.uc.GetTrends(ctx, 0)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(trends) != len(tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, trends)
+			}
+
+			for i, trend := range trends {
+				want := tt.want[i]
+
+				if trend.Tag != want.Tag || trend.Uses != want.Uses || math.Abs(trend.Score-want.Score) > 1e-9 {
+					t.Fatalf("expected %+v, got %+v", tt.want, trends)
+				}
+			}
+		})
+	}
+}
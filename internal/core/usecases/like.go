@@ -0,0 +1,91 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func (u userUseCase) LikeTweet(ctx context.Context, userId int, tweetId int) error {
+
+	if err := u.repoImpl.LikeTweet(ctx, userId, tweetId); err != nil {
+		return err
+	}
+
+	// the store notifies the author of a like again and again only once
+	if tweet, err := u.repoImpl.GetTweetById(ctx, tweetId); err == nil {
+		u.notify(ctx, domain.Notification{UserId: tweet.AuthorId, Kind: domain.NotificationLike, ActorId: userId, TweetId: tweetId})
+	}
+
+	return nil
+}
+
+func (u userUseCase) UnlikeTweet(ctx context.Context, userId int, tweetId int) error {
+	return u.repoImpl.UnlikeTweet(ctx, userId, tweetId)
+}
+
+func (u userUseCase) ListTweetLikers(ctx context.Context, tweetId int, page domain.PageRequest) (domain.UserPage, error) {
+
+	if _, err := u.repoImpl.GetTweetById(ctx, tweetId); err != nil {
+		return domain.UserPage{}, err
+	}
+
+	return u.pageUsers(ctx, page, func(ctx context.Context, afterId int, limit int) ([]int, error) {
+		return u.repoImpl.ListTweetLikers(ctx, tweetId, afterId, limit)
+	})
+}
+
+func (u userUseCase) ListUserLikes(ctx context.Context, userId int, page domain.PageRequest) (domain.TweetPage, error) {
+
+	if _, err := u.repoImpl.GetUserById(ctx, userId); err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	beforeId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	// fetch one extra id to find out whether there is a next page
+	ids, err := u.repoImpl.ListUserLikes(ctx, userId, beforeId, limit+1)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	result := domain.TweetPage{Tweets: make([]domain.Tweet, 0, len(ids))}
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+		result.NextCursor = encodeCursor(ids[limit-1])
+	}
+
+	for _, id := range ids {
+		tweet, err := u.repoImpl.GetTweetById(ctx, id)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// deleted while we were paging
+			continue
+		}
+
+		if err != nil {
+			return domain.TweetPage{}, err
+		}
+
+		result.Tweets = append(result.Tweets, tweet)
+	}
+
+	return result, nil
+}
+
+func (u userUseCase) GetLikeStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.LikeStats, error) {
+	return u.repoImpl.LikeStats(ctx, viewerId, tweetIds)
+}
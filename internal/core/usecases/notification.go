@@ -0,0 +1,107 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func (u userUseCase) ListNotifications(ctx context.Context, userId int, unreadOnly bool, page domain.PageRequest) (domain.NotificationPage, error) {
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+
+	beforeId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+
+	// fetch one extra notification to find out whether there is a next page
+	notifications, err := u.notifications.List(ctx, userId, unreadOnly, beforeId, limit+1)
+
+	if err != nil {
+		return domain.NotificationPage{}, err
+	}
+
+	result := domain.NotificationPage{Notifications: notifications}
+
+	if len(notifications) > limit {
+		result.Notifications = notifications[:limit]
+		result.NextCursor = encodeCursor(notifications[limit-1].Id)
+	}
+
+	return result, nil
+}
+
+func (u userUseCase) MarkNotificationsRead(ctx context.Context, userId int, ids []int) error {
+	return u.notifications.MarkRead(ctx, userId, ids)
+}
+
+func (u userUseCase) CountUnreadNotifications(ctx context.Context, userId int) (int, error) {
+	return u.notifications.CountUnread(ctx, userId)
+}
+
+// notifyTweetPosted tells the author of the parent about a reply and every
+// mentioned user about the mention, once each
+func (u userUseCase) notifyTweetPosted(ctx context.Context, tweet domain.Tweet, parentAuthorId int) {
+	notified := map[int]bool{tweet.AuthorId: true}
+
+	if parentAuthorId != 0 && !notified[parentAuthorId] {
+		notified[parentAuthorId] = true
+		u.notify(ctx, domain.Notification{UserId: parentAuthorId, Kind: domain.NotificationReply, ActorId: tweet.AuthorId, TweetId: tweet.TweetId})
+	}
+
+	for _, userId := range u.mentionedUserIds(ctx, extractMentions(tweet.Body)) {
+		if !notified[userId] {
+			notified[userId] = true
+			u.notify(ctx, domain.Notification{UserId: userId, Kind: domain.NotificationMention, ActorId: tweet.AuthorId, TweetId: tweet.TweetId})
+		}
+	}
+}
+
+// mentionedUserIds resolves mentioned handles to the ids of their users,
+// skipping handles nobody holds
+func (u userUseCase) mentionedUserIds(ctx context.Context, handles []string) []int {
+	userIds := make([]int, 0, len(handles))
+
+	for _, handle := range handles {
+		if !validHandle(handle) {
+			continue
+		}
+
+		userId, err := u.repoImpl.GetUserIdByHandle(ctx, handle)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Couldn't resolve mention @%s: %s", handle, err)
+			continue
+		}
+
+		userIds = append(userIds, userId)
+	}
+
+	return userIds
+}
+
+// notify records a notification. Notifications are a side effect, failing to
+// record one doesn't fail the action that caused it.
+func (u userUseCase) notify(ctx context.Context, notification domain.Notification) {
+	if notification.UserId == notification.ActorId {
+		return
+	}
+
+	notification.CreatedAt = u.clock.Now()
+
+	if _, err := u.notifications.Add(ctx, notification); err != nil {
+		log.Printf("Couldn't notify user %d: %s", notification.UserId, err)
+	}
+}
@@ -0,0 +1,59 @@
+package usecases
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	cursorPrefix = "after:"
+)
+
+var errInvalidCursor = fmt.Errorf("%w: invalid cursor", domain.ErrValidation)
+
+// pageLimit applies the default and the upper bound to a requested page size
+func pageLimit(page domain.PageRequest) (int, error) {
+	switch {
+	case page.Limit < 0:
+		return 0, fmt.Errorf("%w: limit must be positive", domain.ErrValidation)
+	case page.Limit == 0:
+		return defaultPageSize, nil
+	case page.Limit > maxPageSize:
+		return maxPageSize, nil
+	default:
+		return page.Limit, nil
+	}
+}
+
+// encodeCursor turns the key of the last item on a page into an opaque cursor
+func encodeCursor(key int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(key)))
+}
+
+// decodeCursor returns the key a cursor points after, 0 for the first page
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errInvalidCursor
+	}
+
+	key, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+
+	if err != nil || key <= 0 {
+		return 0, errInvalidCursor
+	}
+
+	return key, nil
+}
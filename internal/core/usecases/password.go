@@ -0,0 +1,127 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL is how long an emailed password reset token stays valid
+const passwordResetTTL = time.Hour
+
+var errInvalidResetToken = fmt.Errorf("%w: invalid or expired password reset token", domain.ErrUnauthenticated)
+
+func (u userUseCase) RequestPasswordReset(ctx context.Context, email string) error {
+
+	email = strings.TrimSpace(email)
+
+	if email == "" {
+		return errEmptyEmail
+	}
+
+	userId, err := u.repoImpl.GetUserId(ctx, email)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		// don't reveal which emails are registered
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	secret, err := randomHex(32)
+
+	if err != nil {
+		return err
+	}
+
+	now := u.clock.Now()
+	err = u.repoImpl.SavePasswordReset(ctx, domain.PasswordReset{
+		Hash:      hashSecret(secret),
+		UserId:    userId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	u.sendEmail(ctx, domain.Email{
+		To:      email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your Chirpy account. If it was you, "+
+				"send this token along with your new password to /api/password/reset "+
+				"within %d minutes:\n\n%s\n\nIf it wasn't you, ignore this email, your password stays as it is.",
+			int(passwordResetTTL.Minutes()), secret,
+		),
+	})
+
+	return nil
+}
+
+func (u userUseCase) ResetPassword(ctx context.Context, token string, password string) error {
+
+	// checked first so a rejected password doesn't spend the token
+	if password == "" {
+		return errEmptyPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return err
+	}
+
+	// an expired token is turned down before it spends the user's other tokens
+	now := u.clock.Now()
+	reset, err := u.repoImpl.UsePasswordReset(ctx, hashSecret(token), now)
+
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrTokenUsed) || errors.Is(err, domain.ErrTokenExpired) {
+		return errInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := u.repoImpl.UpdateUser(ctx, reset.UserId, domain.User{HashedPassword: hashedPassword, UpdatedAt: now}); err != nil {
+		return err
+	}
+
+	// whoever got hold of the old password is logged out everywhere
+	if err := u.RevokeSessions(ctx, reset.UserId, ""); err != nil {
+		return err
+	}
+
+	user, err := u.repoImpl.GetUserById(ctx, reset.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	u.sendEmail(ctx, domain.Email{
+		To:      user.Email,
+		Subject: "Your Chirpy password was changed",
+		Body:    "The password of your Chirpy account was just reset and every session was logged out.",
+	})
+
+	return nil
+}
+
+// sendEmail hands an email to the mailer. Failures are only logged, whether
+// an email went out must not change the answer to the request.
+func (u userUseCase) sendEmail(ctx context.Context, email domain.Email) {
+	if err := u.mailer.Send(ctx, email); err != nil {
+		log.Printf("Couldn't send %q: %s", email.Subject, err)
+	}
+}
@@ -0,0 +1,135 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// requestReset asks for a password reset and returns the token emailed
+func requestReset(t *testing.T, env testEnv, email string) string {
+	t.Helper()
+
+	before := len(env.mailer.Sent())
+
+	if err := env.uc.RequestPasswordReset(context.Background(), email); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := env.mailer.Sent()
+
+	if len(sent) != before+1 || sent[len(sent)-1].To != email {
+		t.Fatalf("expected one email to %s, got %+v", email, sent[before:])
+	}
+
+	// the token sits alone on the line after the first blank one
+	paragraphs := strings.Split(sent[len(sent)-1].Body, "\n\n")
+
+	if len(paragraphs) < 2 {
+		t.Fatalf("no token in %q", sent[len(sent)-1].Body)
+	}
+
+	return paragraphs[1]
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	env := newTestEnv()
+
+	// the answer is the same as for a registered email, only nothing is sent
+	if err := env.uc.RequestPasswordReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sent := env.mailer.Sent(); len(sent) != 0 {
+		t.Fatalf("expected no email, got %+v", sent)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name string
+		// reset uses the two tokens emailed and returns the error of the last reset
+		reset   func(t *testing.T, env testEnv, tokens []string) error
+		wantErr error
+		// wantPassword is the password the user logs in with afterwards,
+		// sessions are logged out when it changed
+		wantPassword string
+	}{
+		{"Once", func(t *testing.T, env testEnv, tokens []string) error {
+			return env.uc.ResetPassword(context.Background(), tokens[0], "new")
+		}, nil, "new"},
+		{"SingleUse", func(t *testing.T, env testEnv, tokens []string) error {
+			if err := env.uc.ResetPassword(context.Background(), tokens[0], "new"); err != nil {
+				t.Fatal(err)
+			}
+			return env.uc.ResetPassword(context.Background(), tokens[0], "newer")
+		}, errInvalidResetToken, "new"},
+		{"UsingOneSpendsTheOthers", func(t *testing.T, env testEnv, tokens []string) error {
+			if err := env.uc.ResetPassword(context.Background(), tokens[1], "new"); err != nil {
+				t.Fatal(err)
+			}
+			return env.uc.ResetPassword(context.Background(), tokens[0], "newer")
+		}, errInvalidResetToken, "new"},
+		{"Expired", func(t *testing.T, env testEnv, tokens []string) error {
+			env.clock.Advance(passwordResetTTL)
+			return env.uc.ResetPassword(context.Background(), tokens[0], "new")
+		}, errInvalidResetToken, "secret"},
+		{"ExpiredDoesntSpendFreshToken", func(t *testing.T, env testEnv, tokens []string) error {
+			env.clock.Advance(passwordResetTTL)
+			fresh := requestReset(t, env, "reset@example.com")
+
+			if err := env.uc.ResetPassword(context.Background(), tokens[0], "stale"); err != errInvalidResetToken {
+				t.Fatalf("expected the expired token to be turned down, got %v", err)
+			}
+			return env.uc.ResetPassword(context.Background(), fresh, "new")
+		}, nil, "new"},
+		{"UnknownToken", func(t *testing.T, env testEnv, tokens []string) error {
+			return env.uc.ResetPassword(context.Background(), "guess", "new")
+		}, errInvalidResetToken, "secret"},
+		{"EmptyPasswordKeepsTheToken", func(t *testing.T, env testEnv, tokens []string) error {
+			if err := env.uc.ResetPassword(context.Background(), tokens[0], ""); !errors.Is(err, domain.ErrValidation) {
+				t.Fatalf("expected an empty password to be rejected, got %v", err)
+			}
+			return env.uc.ResetPassword(context.Background(), tokens[0], "new")
+		}, nil, "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			user, err := env.uc.CreateUser(ctx, "reset@example.com", "secret", domain.Profile{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sessionId, err := env.uc.IssueRefreshToken(ctx, user.ID, "refresh", env.clock.Now().Add(24*time.Hour), domain.Client{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens := []string{requestReset(t, env, user.Email), requestReset(t, env, user.Email)}
+
+			if err := tt.reset(t, env, tokens); err != tt.wantErr {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if _, err := env.uc.LoginUser(ctx, user.Email, tt.wantPassword); err != nil {
+				t.Fatalf("expected the password to be %q, got %v", tt.wantPassword, err)
+			}
+
+			// a reset logs every session out
+			loggedOut := tt.wantPassword != "secret"
+
+			if err := env.uc.CheckSession(ctx, user.ID, sessionId); (err != nil) != loggedOut {
+				t.Fatalf("expected the session to be logged out: %v, got %v", loggedOut, err)
+			}
+		})
+	}
+}
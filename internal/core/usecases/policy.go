@@ -0,0 +1,84 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// permission is a privileged action, one that reaches beyond the actor's
+// own account and tweets
+type permission string
+
+const (
+	permDeleteAnyTweet permission = "delete_any_tweet"
+	permSuspendUsers   permission = "suspend_users"
+	permGrantRoles     permission = "grant_roles"
+	permReadAuditLog   permission = "read_audit_log"
+)
+
+// rolePermissions is the policy, the privileged actions each role may take.
+// Plain users take none.
+var rolePermissions = map[domain.Role][]permission{
+	domain.RoleModerator: {permDeleteAnyTweet, permSuspendUsers},
+	domain.RoleAdmin:     {permDeleteAnyTweet, permSuspendUsers, permGrantRoles, permReadAuditLog},
+}
+
+// allowed tells whether the policy lets the user take the action
+func allowed(user domain.User, action permission) bool {
+	if user.Suspended {
+		return false
+	}
+
+	for _, granted := range rolePermissions[user.Role] {
+		if granted == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorize loads the actor and checks the policy lets them take the action
+func (u userUseCase) authorize(ctx context.Context, actorId int, action permission) (domain.User, error) {
+
+	actor, err := u.repoImpl.GetUserById(ctx, actorId)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if !allowed(actor, action) {
+		return domain.User{}, domain.ErrNotPermitted
+	}
+
+	return actor, nil
+}
+
+// authorizeOver is authorize for actions against another user, who the actor
+// has to outrank. Nobody acts against themselves this way. It returns the
+// actor and the target.
+func (u userUseCase) authorizeOver(ctx context.Context, actorId int, targetId int, action permission) (domain.User, domain.User, error) {
+
+	actor, err := u.authorize(ctx, actorId, action)
+
+	if err != nil {
+		return domain.User{}, domain.User{}, err
+	}
+
+	if actorId == targetId {
+		return domain.User{}, domain.User{}, errSelfModeration
+	}
+
+	target, err := u.repoImpl.GetUserById(ctx, targetId)
+
+	if err != nil {
+		return domain.User{}, domain.User{}, err
+	}
+
+	if !actor.Role.Outranks(target.Role) {
+		return domain.User{}, domain.User{}, domain.ErrNotPermitted
+	}
+
+	return actor, target, nil
+}
@@ -0,0 +1,71 @@
+package usecases
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// Longest display name, bio and location a profile can hold, in characters
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxLocationLength    = 30
+)
+
+var (
+	handlePattern = regexp.MustCompile(`^\w{3,15}$`)
+	digitsPattern = regexp.MustCompile(`^[0-9]+$`)
+)
+
+func (u userUseCase) UpdateProfile(ctx context.Context, userId int, profile domain.Profile) error {
+
+	profile, err := normalizeProfile(profile)
+
+	if err != nil {
+		return err
+	}
+
+	return u.repoImpl.UpdateProfile(ctx, userId, domain.User{Profile: profile, UpdatedAt: u.clock.Now()})
+}
+
+func (u userUseCase) GetUserByHandle(ctx context.Context, handle string) (domain.User, error) {
+
+	userId, err := u.repoImpl.GetUserIdByHandle(ctx, strings.TrimPrefix(handle, "@"))
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return u.repoImpl.GetUserById(ctx, userId)
+}
+
+// normalizeProfile trims a profile and checks it against the profile rules.
+// The handle may be written with its leading @ and may be left empty.
+func normalizeProfile(profile domain.Profile) (domain.Profile, error) {
+	profile.Handle = strings.TrimPrefix(strings.TrimSpace(profile.Handle), "@")
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.Bio = strings.TrimSpace(profile.Bio)
+	profile.Location = strings.TrimSpace(profile.Location)
+
+	if profile.Handle != "" && !validHandle(profile.Handle) {
+		return profile, domain.ErrInvalidHandle
+	}
+
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength ||
+		utf8.RuneCountInString(profile.Bio) > maxBioLength ||
+		utf8.RuneCountInString(profile.Location) > maxLocationLength {
+		return profile, domain.ErrProfileTooLong
+	}
+
+	return profile, nil
+}
+
+// validHandle reports whether handle is 3 to 15 letters, digits or
+// underscores. All-digit handles are refused so they never read as user ids.
+func validHandle(handle string) bool {
+	return handlePattern.MatchString(handle) && !digitsPattern.MatchString(handle)
+}
@@ -0,0 +1,91 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestUpdateProfileHandle(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle string
+		// wantErr is the error expected, nil for success
+		wantErr error
+		// wantHandle is the handle kept, the old one when the update fails
+		wantHandle string
+	}{
+		{"Shortest", "abc", nil, "abc"},
+		{"TooShort", "ab", domain.ErrInvalidHandle, "Mine"},
+		{"Longest", strings.Repeat("a", 15), nil, strings.Repeat("a", 15)},
+		{"TooLong", strings.Repeat("a", 16), domain.ErrInvalidHandle, "Mine"},
+		{"Underscores", "__under_score__", nil, "__under_score__"},
+		{"Hyphen", "new-handle", domain.ErrInvalidHandle, "Mine"},
+		{"Space", "new handle", domain.ErrInvalidHandle, "Mine"},
+		{"NonAscii", "café", domain.ErrInvalidHandle, "Mine"},
+		{"OnlyDigits", "12345", domain.ErrInvalidHandle, "Mine"},
+		{"DigitsAndUnderscore", "_12345", nil, "_12345"},
+		{"LeadingAt", " @newhandle ", nil, "newhandle"},
+		// handles are unique ignoring case, but keep the case they were given
+		{"TakenSameCase", "Taken", domain.ErrHandleTaken, "Mine"},
+		{"TakenOtherCase", "tAKEN", domain.ErrHandleTaken, "Mine"},
+		{"OwnHandleRecased", "MINE", nil, "MINE"},
+		{"Cleared", "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+
+			other, user := mustUser(t, env.repo, "taken"), mustUser(t, env.repo, "mine")
+
+			if err := env.uc.UpdateProfile(ctx, other.ID, domain.Profile{Handle: "Taken"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := env.uc.UpdateProfile(ctx, user.ID, domain.Profile{Handle: "Mine"}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := env.uc.UpdateProfile(ctx, user.ID, domain.Profile{Handle: tt.handle})
+
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if got, _ := env.uc.GetUserById(ctx, user.ID); got.Handle != tt.wantHandle {
+				t.Fatalf("expected the handle to be %q, got %q", tt.wantHandle, got.Handle)
+			}
+
+			if tt.wantHandle == "" {
+				return
+			}
+
+			// the handle is found whatever case it is looked up in
+			for _, lookup := range []string{tt.wantHandle, "@" + strings.ToUpper(tt.wantHandle)} {
+				found, err := env.uc.GetUserByHandle(ctx, lookup)
+
+				if err != nil || found.ID != user.ID {
+					t.Fatalf("expected %q to find user %d, got %d %v", lookup, user.ID, found.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUserHandleTaken(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+
+	if _, err := env.uc.CreateUser(ctx, "first@example.com", "secret", domain.Profile{Handle: "Chirper"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.uc.CreateUser(ctx, "second@example.com", "secret", domain.Profile{Handle: "@CHIRPER"}); !errors.Is(err, domain.ErrHandleTaken) {
+		t.Fatalf("expected the handle to be taken, got %v", err)
+	}
+}
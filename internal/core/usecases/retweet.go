@@ -0,0 +1,11 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func (u userUseCase) GetRetweetStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.RetweetStats, error) {
+	return u.repoImpl.RetweetStats(ctx, viewerId, tweetIds)
+}
@@ -0,0 +1,179 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// maxSearchQueryLength bounds how long a search query can be
+const maxSearchQueryLength = 256
+
+var (
+	errEmptySearch     = fmt.Errorf("%w: empty search query", domain.ErrValidation)
+	errLongSearch      = fmt.Errorf("%w: search query too long", domain.ErrValidation)
+	errInvalidSearchBy = fmt.Errorf("%w: unknown search order", domain.ErrValidation)
+	errInvalidFrom     = fmt.Errorf("%w: from: needs a user id or handle", domain.ErrValidation)
+)
+
+func (u userUseCase) SearchTweets(ctx context.Context, query string, order domain.SearchOrder, page domain.PageRequest) (domain.TweetPage, error) {
+
+	searchQuery, fromHandle, err := parseSearchQuery(query)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	if fromHandle != "" {
+		searchQuery.AuthorId, err = u.repoImpl.GetUserIdByHandle(ctx, fromHandle)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// nobody holds the handle, so nobody tweeted under it
+			return domain.TweetPage{Tweets: []domain.Tweet{}}, nil
+		}
+
+		if err != nil {
+			return domain.TweetPage{}, err
+		}
+	}
+
+	switch order {
+	case "":
+		searchQuery.Order = domain.SearchByRelevance
+	case domain.SearchByRelevance, domain.SearchByRecency:
+		searchQuery.Order = order
+	default:
+		return domain.TweetPage{}, errInvalidSearchBy
+	}
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	afterKey, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	// fetch one extra id to find out whether there is a next page
+	ids, err := u.search.Search(ctx, searchQuery, afterKey, limit+1)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	result := domain.TweetPage{Tweets: make([]domain.Tweet, 0, len(ids))}
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+
+		if searchQuery.Order == domain.SearchByRecency {
+			result.NextCursor = encodeCursor(ids[limit-1])
+		} else {
+			result.NextCursor = encodeCursor(afterKey + limit)
+		}
+	}
+
+	for _, id := range ids {
+		tweet, err := u.repoImpl.GetTweetById(ctx, id)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// deleted after it was found
+			continue
+		}
+
+		if err != nil {
+			return domain.TweetPage{}, err
+		}
+
+		result.Tweets = append(result.Tweets, tweet)
+	}
+
+	return result, nil
+}
+
+// parseSearchQuery reads a search query. Words match anywhere in a tweet,
+// "quoted phrases" match word for word and from:<user id> or from:@handle
+// keeps the tweets of one author. A handle is handed back for the caller to
+// resolve.
+func parseSearchQuery(query string) (domain.SearchQuery, string, error) {
+	if len(query) > maxSearchQueryLength {
+		return domain.SearchQuery{}, "", errLongSearch
+	}
+
+	fromHandle := ""
+
+	parsed := domain.SearchQuery{}
+
+	// every odd part sits between quotes, an unbalanced quote runs to the end
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				parsed.Phrases = append(parsed.Phrases, phrase)
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			author, isFilter := strings.CutPrefix(strings.ToLower(word), "from:")
+
+			if !isFilter {
+				parsed.Terms = append(parsed.Terms, word)
+				continue
+			}
+
+			if handle := strings.TrimPrefix(author, "@"); validHandle(handle) {
+				parsed.AuthorId, fromHandle = 0, handle
+				continue
+			}
+
+			authorId, err := strconv.Atoi(author)
+
+			if err != nil || authorId <= 0 {
+				return domain.SearchQuery{}, "", errInvalidFrom
+			}
+
+			parsed.AuthorId, fromHandle = authorId, ""
+		}
+	}
+
+	if len(parsed.Terms) == 0 && len(parsed.Phrases) == 0 && parsed.AuthorId == 0 && fromHandle == "" {
+		return domain.SearchQuery{}, "", errEmptySearch
+	}
+
+	return parsed, fromHandle, nil
+}
+
+// RebuildSearchIndex indexes every stored tweet, for search indexes that
+// don't outlive the process
+func RebuildSearchIndex(ctx context.Context, repo ports.IRepository, index ports.ITweetSearchIndex) error {
+	const batchSize = 500
+
+	for afterId := 0; ; {
+		tweets, err := repo.ListTweets(ctx, domain.TweetFilter{}, afterId, batchSize)
+
+		if err != nil {
+			return err
+		}
+
+		for _, tweet := range tweets {
+			if err := index.Index(ctx, tweet); err != nil {
+				return err
+			}
+		}
+
+		if len(tweets) < batchSize {
+			return nil
+		}
+
+		afterId = tweets[len(tweets)-1].TweetId
+	}
+}
@@ -0,0 +1,138 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+const (
+	// threadDepth is how many levels of replies a thread loads at once
+	threadDepth = 3
+	// threadPreviewReplies is how many replies each nested reply shows
+	threadPreviewReplies = 3
+)
+
+func (u userUseCase) GetThread(ctx context.Context, tweetId int, page domain.PageRequest) (domain.Thread, error) {
+
+	tweet, err := u.repoImpl.GetTweetById(ctx, tweetId)
+
+	if err != nil {
+		return domain.Thread{}, err
+	}
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.Thread{}, err
+	}
+
+	afterId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.Thread{}, err
+	}
+
+	thread := domain.Thread{Ancestors: make([]domain.Tweet, 0)}
+
+	for parentId := tweet.InReplyTo; parentId != 0; {
+		parent, err := u.repoImpl.GetTweetById(ctx, parentId)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// a deleted tweet cuts the thread
+			break
+		}
+
+		if err != nil {
+			return domain.Thread{}, err
+		}
+
+		thread.Ancestors = append(thread.Ancestors, parent)
+		parentId = parent.InReplyTo
+	}
+
+	slices.Reverse(thread.Ancestors)
+
+	thread.Tweet = tweet
+	thread.Replies, thread.NextCursor, err = u.threadReplies(ctx, tweetId, afterId, limit, threadDepth)
+
+	if err != nil {
+		return domain.Thread{}, err
+	}
+
+	if err := u.countThreadReplies(ctx, &thread.ThreadNode); err != nil {
+		return domain.Thread{}, err
+	}
+
+	return thread, nil
+}
+
+// threadReplies loads a page of replies to a tweet and, depth levels down,
+// the first few replies to each of them
+func (u userUseCase) threadReplies(ctx context.Context, tweetId int, afterId int, limit int, depth int) ([]domain.ThreadNode, string, error) {
+
+	// fetch one extra reply to find out whether there is a next page
+	replies, err := u.repoImpl.ListReplies(ctx, tweetId, afterId, limit+1)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+
+	if len(replies) > limit {
+		replies = replies[:limit]
+		nextCursor = encodeCursor(replies[limit-1].TweetId)
+	}
+
+	nodes := make([]domain.ThreadNode, 0, len(replies))
+
+	for _, reply := range replies {
+		node := domain.ThreadNode{Tweet: reply, Replies: make([]domain.ThreadNode, 0)}
+
+		if depth > 1 {
+			node.Replies, node.NextCursor, err = u.threadReplies(ctx, reply.TweetId, 0, threadPreviewReplies, depth-1)
+
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nextCursor, nil
+}
+
+// countThreadReplies fills in the reply counts of a whole tree in one lookup
+func (u userUseCase) countThreadReplies(ctx context.Context, root *domain.ThreadNode) error {
+	nodes := []*domain.ThreadNode{}
+	var collect func(node *domain.ThreadNode)
+	collect = func(node *domain.ThreadNode) {
+		nodes = append(nodes, node)
+		for i := range node.Replies {
+			collect(&node.Replies[i])
+		}
+	}
+	collect(root)
+
+	tweetIds := make([]int, 0, len(nodes))
+
+	for _, node := range nodes {
+		tweetIds = append(tweetIds, node.Tweet.TweetId)
+	}
+
+	counts, err := u.repoImpl.CountReplies(ctx, tweetIds)
+
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		node.ReplyCount = counts[node.Tweet.TweetId]
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// TimelineStrategy builds home timelines: the tweets of everyone a user
+// follows plus their own, newest first. The use case keeps it informed of new
+// tweets and follow graph changes so it can precompute if it wants to.
+type TimelineStrategy interface {
+	TweetPosted(ctx context.Context, tweet domain.Tweet) error
+	FollowChanged(ctx context.Context, followerId int, followeeId int) error
+	// HomeTimeline returns up to limit tweets older than beforeId (0 for the newest)
+	HomeTimeline(ctx context.Context, userId int, beforeId int, limit int) ([]domain.Tweet, error)
+}
+
+// maxMaterializedTimeline caps how many tweets fan-out-on-write keeps per user
+const maxMaterializedTimeline = 800
+
+func (u userUseCase) HomeTimeline(ctx context.Context, userId int, page domain.PageRequest) (domain.TweetPage, error) {
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	beforeId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	// fetch one extra tweet to find out whether there is a next page
+	tweets, err := u.timeline.HomeTimeline(ctx, userId, beforeId, limit+1)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	result := domain.TweetPage{Tweets: tweets}
+
+	if len(tweets) > limit {
+		result.Tweets = tweets[:limit]
+		result.NextCursor = encodeCursor(tweets[limit-1].TweetId)
+	}
+
+	return result, nil
+}
+
+// forEachFollowing calls fn for every user userId follows
+func forEachFollowing(ctx context.Context, repo ports.IRepository, userId int, fn func(id int) error) error {
+	return forEachPage(ctx, userId, repo.ListFollowing, fn)
+}
+
+// forEachFollower calls fn for every user following userId
+func forEachFollower(ctx context.Context, repo ports.IRepository, userId int, fn func(id int) error) error {
+	return forEachPage(ctx, userId, repo.ListFollowers, fn)
+}
+
+func forEachPage(
+	ctx context.Context,
+	userId int,
+	list func(ctx context.Context, userId int, afterId int, limit int) ([]int, error),
+	fn func(id int) error,
+) error {
+	afterId := 0
+
+	for {
+		ids, err := list(ctx, userId, afterId, maxPageSize)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := fn(id); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) < maxPageSize {
+			return nil
+		}
+
+		afterId = ids[len(ids)-1]
+	}
+}
+
+// Fan-out-on-read: nothing is precomputed, every read merges the newest
+// tweets of the user and everyone they follow. Cheap writes, reads cost grows
+// with the number of accounts followed.
+func ProvideFanOutOnReadTimeline(repoImplementation ports.IRepository) TimelineStrategy {
+	return fanOutOnReadTimeline{repoImpl: repoImplementation}
+}
+
+type fanOutOnReadTimeline struct {
+	repoImpl ports.IRepository
+}
+
+func (f fanOutOnReadTimeline) TweetPosted(ctx context.Context, tweet domain.Tweet) error {
+	return nil
+}
+
+func (f fanOutOnReadTimeline) FollowChanged(ctx context.Context, followerId int, followeeId int) error {
+	return nil
+}
+
+func (f fanOutOnReadTimeline) HomeTimeline(ctx context.Context, userId int, beforeId int, limit int) ([]domain.Tweet, error) {
+	authors := []int{userId}
+
+	err := forEachFollowing(ctx, f.repoImpl, userId, func(id int) error {
+		authors = append(authors, id)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// every author contributes at most limit tweets, merge them newest first
+	merged := make([]domain.Tweet, 0, limit)
+
+	for _, authorId := range authors {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		tweets, err := f.repoImpl.ListTweets(ctx, domain.TweetFilter{AuthorId: authorId, Descending: true}, beforeId, limit)
+
+		if err != nil {
+			return nil, err
+		}
+
+		merged = mergeNewestFirst(merged, tweets, limit)
+	}
+
+	return merged, nil
+}
+
+// mergeNewestFirst merges two tweet slices sorted by descending id, keeping at most limit
+func mergeNewestFirst(a, b []domain.Tweet, limit int) []domain.Tweet {
+	merged := make([]domain.Tweet, 0, min(len(a)+len(b), limit))
+
+	for len(merged) < limit && (len(a) > 0 || len(b) > 0) {
+		if len(b) == 0 || (len(a) > 0 && a[0].TweetId > b[0].TweetId) {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+
+	return merged
+}
+
+// Fan-out-on-write: every new tweet is pushed into the materialized timeline
+// of its author and followers, so reads are a single lookup. Timelines are
+// built on first read and rebuilt after the follow graph changes.
+func ProvideFanOutOnWriteTimeline(repoImplementation ports.IRepository, cache ports.ITimelineCache) TimelineStrategy {
+	return fanOutOnWriteTimeline{
+		repoImpl: repoImplementation,
+		cache:    cache,
+		onRead:   fanOutOnReadTimeline{repoImpl: repoImplementation},
+	}
+}
+
+type fanOutOnWriteTimeline struct {
+	repoImpl ports.IRepository
+	cache    ports.ITimelineCache
+	// onRead builds a timeline from scratch the first time it's read
+	onRead fanOutOnReadTimeline
+}
+
+func (f fanOutOnWriteTimeline) TweetPosted(ctx context.Context, tweet domain.Tweet) error {
+	f.cache.Push(ctx, tweet.AuthorId, tweet.TweetId, maxMaterializedTimeline)
+
+	return forEachFollower(ctx, f.repoImpl, tweet.AuthorId, func(id int) error {
+		f.cache.Push(ctx, id, tweet.TweetId, maxMaterializedTimeline)
+		return nil
+	})
+}
+
+func (f fanOutOnWriteTimeline) FollowChanged(ctx context.Context, followerId int, followeeId int) error {
+	f.cache.Invalidate(ctx, followerId)
+	return nil
+}
+
+func (f fanOutOnWriteTimeline) HomeTimeline(ctx context.Context, userId int, beforeId int, limit int) ([]domain.Tweet, error) {
+	tweetIds, version, ok := f.cache.Get(ctx, userId)
+
+	if !ok {
+		newest, err := f.onRead.HomeTimeline(ctx, userId, 0, maxMaterializedTimeline)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tweetIds = make([]int, len(newest))
+		for i, tweet := range newest {
+			tweetIds[len(newest)-1-i] = tweet.TweetId
+		}
+
+		// a tweet pushed or a follow changed while this was being built
+		// makes it stale, the next read builds it again
+		f.cache.Set(ctx, userId, tweetIds, version)
+	}
+
+	tweets := make([]domain.Tweet, 0, limit)
+
+	for i := len(tweetIds) - 1; i >= 0 && len(tweets) < limit; i-- {
+		if beforeId > 0 && tweetIds[i] >= beforeId {
+			continue
+		}
+
+		tweet, err := f.repoImpl.GetTweetById(ctx, tweetIds[i])
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// deleted since it was pushed
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		tweets = append(tweets, tweet)
+	}
+
+	if len(tweets) < limit && len(tweetIds) >= maxMaterializedTimeline {
+		// paged past what's materialized, fall back to merging on read
+		olderThan := tweetIds[0]
+		if beforeId > 0 && beforeId < olderThan {
+			olderThan = beforeId
+		}
+
+		older, err := f.onRead.HomeTimeline(ctx, userId, olderThan, limit-len(tweets))
+
+		if err != nil {
+			return nil, err
+		}
+
+		tweets = append(tweets, older...)
+	}
+
+	return tweets, nil
+}
@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	adapters "github.com/anandh86/chirpy/internal/repositories"
+)
+
+func TestFanOutOnWriteRebuildRace(t *testing.T) {
+	tests := []struct {
+		name string
+		// during runs once the first rebuild has read the author's tweets and
+		// returns the tweet the reader's timeline must show from then on
+		during func(t *testing.T, repo *fakeRepository, timeline TimelineStrategy, reader, author, stranger int) int
+	}{
+		{"TweetPosted", func(t *testing.T, repo *fakeRepository, timeline TimelineStrategy, reader, author, stranger int) int {
+			tweet := mustPost(t, repo, timeline, author)
+			return tweet.TweetId
+		}},
+		{"FollowChanged", func(t *testing.T, repo *fakeRepository, timeline TimelineStrategy, reader, author, stranger int) int {
+			ctx := context.Background()
+
+			if err := repo.Follow(ctx, reader, stranger); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := timeline.FollowChanged(ctx, reader, stranger); err != nil {
+				t.Fatal(err)
+			}
+
+			tweets, _ := repo.ListTweets(ctx, domain.TweetFilter{AuthorId: stranger}, 0, 1)
+			return tweets[0].TweetId
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newFakeRepository()
+			timeline := ProvideFanOutOnWriteTimeline(repo, adapters.ProvideInMemoryTimelineCache())
+
+			reader, author, stranger := mustUser(t, repo, "reader"), mustUser(t, repo, "author"), mustUser(t, repo, "stranger")
+
+			if err := repo.Follow(ctx, reader.ID, author.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			mustPost(t, repo, timeline, author.ID)
+			mustPost(t, repo, timeline, stranger.ID)
+
+			var want int
+			repo.afterListTweets = func(filter domain.TweetFilter) {
+				if filter.AuthorId == author.ID && want == 0 {
+					want = tt.during(t, repo, timeline, reader.ID, author.ID, stranger.ID)
+				}
+			}
+
+			if _, err := timeline.HomeTimeline(ctx, reader.ID, 0, 10); err != nil {
+				t.Fatal(err)
+			}
+
+			tweets, err := timeline.HomeTimeline(ctx, reader.ID, 0, 10)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tweet := range tweets {
+				if tweet.TweetId == want {
+					return
+				}
+			}
+
+			t.Fatalf("tweet %d is missing from the timeline %+v", want, tweets)
+		})
+	}
+}
+
+func mustUser(t *testing.T, repo *fakeRepository, name string) domain.User {
+	t.Helper()
+
+	user, err := repo.Save(context.Background(), domain.User{Email: name + "@example.com", HashedPassword: []byte("x")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func mustPost(t *testing.T, repo *fakeRepository, timeline TimelineStrategy, authorId int) domain.Tweet {
+	t.Helper()
+
+	ctx := context.Background()
+	tweet, err := repo.SaveTweet(ctx, domain.Tweet{Body: "hello", AuthorId: authorId})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := timeline.TweetPosted(ctx, tweet); err != nil {
+		t.Fatal(err)
+	}
+
+	return tweet
+}
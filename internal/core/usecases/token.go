@@ -0,0 +1,224 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 256
+
+var (
+	errInvalidRefreshToken = fmt.Errorf("%w: invalid refresh token", domain.ErrUnauthenticated)
+	errReusedRefreshToken  = fmt.Errorf("%w: refresh token reused, its login was revoked", domain.ErrUnauthenticated)
+	errSessionEnded        = fmt.Errorf("%w: session logged out", domain.ErrUnauthenticated)
+)
+
+func (u userUseCase) IssueRefreshToken(ctx context.Context, userId int, token string, expiresAt time.Time, client domain.Client) (string, error) {
+
+	familyId, err := newTokenFamilyId()
+
+	if err != nil {
+		return "", err
+	}
+
+	now := u.clock.Now()
+	err = u.repoImpl.SaveRefreshToken(ctx, domain.RefreshToken{
+		Token:      token,
+		UserId:     userId,
+		FamilyId:   familyId,
+		Client:     trimClient(client),
+		LoggedInAt: now,
+		CreatedAt:  now,
+		ExpiresAt:  expiresAt,
+	})
+
+	return familyId, err
+}
+
+func (u userUseCase) RotateRefreshToken(ctx context.Context, token string, next string, expiresAt time.Time, client domain.Client) (string, error) {
+
+	current, err := u.activeRefreshToken(ctx, token)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = u.repoImpl.RotateRefreshToken(ctx, token, domain.RefreshToken{
+		Token:      next,
+		UserId:     current.UserId,
+		FamilyId:   current.FamilyId,
+		Client:     trimClient(client),
+		LoggedInAt: current.LoggedInAt,
+		CreatedAt:  u.clock.Now(),
+		ExpiresAt:  expiresAt,
+	})
+
+	if errors.Is(err, domain.ErrTokenRotated) {
+		// only the latest token of a family is ever handed out, so someone
+		// replaying an older one may have stolen it
+		if err := u.repoImpl.RevokeTokenFamily(ctx, current.FamilyId); err != nil {
+			return "", err
+		}
+
+		log.Printf("Refresh token of user %d reused, revoked token family %s", current.UserId, current.FamilyId)
+		return "", errReusedRefreshToken
+	}
+
+	return current.FamilyId, err
+}
+
+func (u userUseCase) RevokeRefreshToken(ctx context.Context, token string) error {
+
+	current, err := u.activeRefreshToken(ctx, token)
+
+	if err != nil {
+		return err
+	}
+
+	return u.repoImpl.RevokeTokenFamily(ctx, current.FamilyId)
+}
+
+// ListSessions returns the sessions of the user that can still refresh,
+// most recently used first
+func (u userUseCase) ListSessions(ctx context.Context, userId int) ([]domain.Session, error) {
+
+	tokens, err := u.repoImpl.ListRefreshTokens(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.clock.Now()
+	sessions := make([]domain.Session, 0, len(tokens))
+
+	for _, token := range tokens {
+		if !now.Before(token.ExpiresAt) {
+			continue
+		}
+
+		sessions = append(sessions, domain.Session{
+			Id:         token.FamilyId,
+			Client:     token.Client,
+			CreatedAt:  token.LoggedInAt,
+			LastUsedAt: token.CreatedAt,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+func (u userUseCase) CheckSession(ctx context.Context, userId int, sessionId string) error {
+
+	token, err := u.repoImpl.GetLatestRefreshToken(ctx, sessionId)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return errSessionEnded
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if token.UserId != userId || !u.clock.Now().Before(token.ExpiresAt) {
+		return errSessionEnded
+	}
+
+	return nil
+}
+
+func (u userUseCase) RevokeSession(ctx context.Context, userId int, sessionId string) error {
+
+	sessions, err := u.ListSessions(ctx, userId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Id == sessionId {
+			return u.repoImpl.RevokeTokenFamily(ctx, sessionId)
+		}
+	}
+
+	return domain.ErrSessionNotFound
+}
+
+func (u userUseCase) RevokeSessions(ctx context.Context, userId int, keepSessionId string) error {
+
+	sessions, err := u.ListSessions(ctx, userId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Id == keepSessionId {
+			continue
+		}
+
+		if err := u.repoImpl.RevokeTokenFamily(ctx, session.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// activeRefreshToken looks up a refresh token that is neither revoked nor
+// expired. Rotated tokens are returned, rotating them again is what gives
+// reuse away.
+func (u userUseCase) activeRefreshToken(ctx context.Context, token string) (domain.RefreshToken, error) {
+
+	current, err := u.repoImpl.GetRefreshToken(ctx, token)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return current, errInvalidRefreshToken
+	}
+
+	if err != nil {
+		return current, err
+	}
+
+	if current.Revoked || !u.clock.Now().Before(current.ExpiresAt) {
+		return current, errInvalidRefreshToken
+	}
+
+	return current, nil
+}
+
+func trimClient(client domain.Client) domain.Client {
+	if len(client.UserAgent) > maxUserAgentLength {
+		client.UserAgent = strings.ToValidUTF8(client.UserAgent[:maxUserAgentLength], "")
+	}
+
+	return client
+}
+
+func newTokenFamilyId() (string, error) {
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	random := make([]byte, n)
+
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(random), nil
+}
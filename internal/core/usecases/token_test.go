@@ -0,0 +1,107 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+	user := mustUser(t, env.repo, "rotating")
+	expiresAt := env.clock.Now().Add(time.Hour)
+
+	sessionId, err := env.uc.IssueRefreshToken(ctx, user.ID, "first", expiresAt, domain.Client{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSessionId, err := env.uc.IssueRefreshToken(ctx, user.ID, "other", expiresAt, domain.Client{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every refresh swaps the token and stays in the session
+	for _, step := range []struct{ token, next string }{{"first", "second"}, {"second", "third"}} {
+		got, err := env.uc.RotateRefreshToken(ctx, step.token, step.next, expiresAt, domain.Client{})
+
+		if err != nil || got != sessionId {
+			t.Fatalf("rotating %s: expected session %s, got %q (%v)", step.token, sessionId, got, err)
+		}
+	}
+
+	// presenting a swapped token again gives a theft away
+	if _, err := env.uc.RotateRefreshToken(ctx, "first", "stolen", expiresAt, domain.Client{}); !errors.Is(err, errReusedRefreshToken) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+
+	// which ends the whole session, the latest token included
+	if _, err := env.uc.RotateRefreshToken(ctx, "third", "fourth", expiresAt, domain.Client{}); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("expected the latest token of a reused family to be revoked, got %v", err)
+	}
+
+	if err := env.uc.CheckSession(ctx, user.ID, sessionId); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("expected the session to be over, got %v", err)
+	}
+
+	if _, err := env.repo.GetRefreshToken(ctx, "stolen"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("the token swapped in by the reuse was stored: %v", err)
+	}
+
+	// other logins of the user carry on
+	if got, err := env.uc.RotateRefreshToken(ctx, "other", "other-next", expiresAt, domain.Client{}); err != nil || got != otherSessionId {
+		t.Fatalf("expected session %s to carry on, got %q (%v)", otherSessionId, got, err)
+	}
+}
+
+func TestRefreshTokenRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the token to rotate
+		prepare func(t *testing.T, env testEnv, userId int) string
+	}{
+		{"Unknown", func(t *testing.T, env testEnv, userId int) string {
+			return "unknown"
+		}},
+		{"Expired", func(t *testing.T, env testEnv, userId int) string {
+			env.uc.IssueRefreshToken(context.Background(), userId, "expiring", env.clock.Now().Add(time.Hour), domain.Client{})
+			env.clock.Advance(time.Hour)
+			return "expiring"
+		}},
+		{"Revoked", func(t *testing.T, env testEnv, userId int) string {
+			ctx := context.Background()
+			env.uc.IssueRefreshToken(ctx, userId, "revoked", env.clock.Now().Add(time.Hour), domain.Client{})
+
+			if err := env.uc.RevokeRefreshToken(ctx, "revoked"); err != nil {
+				t.Fatal(err)
+			}
+
+			return "revoked"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			user := mustUser(t, env.repo, "rejected")
+			token := tt.prepare(t, env, user.ID)
+
+			_, err := env.uc.RotateRefreshToken(ctx, token, "next", env.clock.Now().Add(time.Hour), domain.Client{})
+
+			if !errors.Is(err, errInvalidRefreshToken) {
+				t.Fatalf("expected an invalid refresh token, got %v", err)
+			}
+
+			if _, err := env.repo.GetRefreshToken(ctx, "next"); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("a rejected rotation stored its successor: %v", err)
+			}
+		})
+	}
+}
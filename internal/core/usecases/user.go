@@ -1,7 +1,10 @@
 package usecases
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
 	"regexp"
 	"strings"
 
@@ -11,69 +14,164 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func ProvideUserUseCase(repoImplementation ports.IRepository) ports.IUseCase {
+var (
+	errCurrentPasswordRequired = fmt.Errorf("%w: changing the email or password takes the current password", domain.ErrValidation)
+	errWrongPassword           = fmt.Errorf("%w: current password is incorrect", domain.ErrForbidden)
+	errEmptyEmail              = fmt.Errorf("%w: email can't be empty", domain.ErrValidation)
+	errEmptyPassword           = fmt.Errorf("%w: password can't be empty", domain.ErrValidation)
+)
+
+func ProvideUserUseCase(
+	repoImplementation ports.IRepository,
+	timeline TimelineStrategy,
+	clock ports.IClock,
+	notifications ports.INotificationStore,
+	search ports.ITweetSearchIndex,
+	mailer ports.IMailer,
+) ports.IUseCase {
 	return &userUseCase{
-		repoImpl: repoImplementation,
+		repoImpl:      repoImplementation,
+		timeline:      timeline,
+		clock:         clock,
+		notifications: notifications,
+		search:        search,
+		mailer:        mailer,
 	}
 }
 
 // userUseCase implements ports.UserUseCase
 type userUseCase struct {
-	repoImpl ports.IRepository
+	repoImpl      ports.IRepository
+	timeline      TimelineStrategy
+	clock         ports.IClock
+	notifications ports.INotificationStore
+	search        ports.ITweetSearchIndex
+	mailer        ports.IMailer
 }
 
-func (u userUseCase) CreateUser(emailid string, password string) (domain.User, error) {
+func (u userUseCase) CreateUser(ctx context.Context, emailid string, password string, profile domain.Profile) (domain.User, error) {
+
+	profile, err := normalizeProfile(profile)
+
+	if err != nil {
+		return domain.User{}, err
+	}
 
 	// Generate a salted hash for the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 
 	if err != nil {
-		return domain.User{}, errors.ErrUnsupported
+		return domain.User{}, err
 	}
 
+	now := u.clock.Now()
 	user := domain.User{
 		Email:          emailid,
 		HashedPassword: hashedPassword,
+		Profile:        profile,
+		Role:           domain.RoleUser,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
-	savedUser, err1 := u.repoImpl.Save(user)
+	savedUser, err1 := u.repoImpl.Save(ctx, user)
 
 	if err1 != nil {
-		return domain.User{}, errors.ErrUnsupported
+		return domain.User{}, err1
 	}
 
 	return savedUser, nil
 }
 
-func (u userUseCase) UpdateUserMembership(id int, isMember bool) error {
-	return u.repoImpl.UpdateUserMembership(id, isMember)
+func (u userUseCase) UpdateUserMembership(ctx context.Context, id int, isMember bool) error {
+	return u.repoImpl.UpdateUserMembership(ctx, id, isMember)
 }
 
-func (u userUseCase) UpdateUser(id int, emailid string, password string) error {
-	// Generate a salted hash for the password
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	user := domain.User{
-		Email:          emailid,
-		HashedPassword: hashedPassword,
+func (u userUseCase) UpdateUser(ctx context.Context, id int, sessionId string, update domain.UserUpdate) (domain.User, error) {
+
+	user, err := u.repoImpl.GetUserById(ctx, id)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if update.Email == nil && update.Password == nil {
+		return user, nil
+	}
+
+	if update.CurrentPassword == "" {
+		return domain.User{}, errCurrentPasswordRequired
+	}
+
+	if !checkPassword(update.CurrentPassword, user.HashedPassword) {
+		return domain.User{}, errWrongPassword
+	}
+
+	// the repository keeps whatever is left empty
+	changes := domain.User{UpdatedAt: u.clock.Now()}
+
+	if update.Email != nil {
+		changes.Email = strings.TrimSpace(*update.Email)
+
+		if changes.Email == "" {
+			return domain.User{}, errEmptyEmail
+		}
+	}
+
+	if update.Password != nil {
+		if *update.Password == "" {
+			return domain.User{}, errEmptyPassword
+		}
+
+		changes.HashedPassword, err = bcrypt.GenerateFromPassword([]byte(*update.Password), bcrypt.DefaultCost)
+
+		if err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	if err := u.repoImpl.UpdateUser(ctx, id, changes); err != nil {
+		return domain.User{}, err
+	}
+
+	// whoever knew the old password is logged out everywhere else
+	if update.Password != nil {
+		if err := u.RevokeSessions(ctx, id, sessionId); err != nil {
+			return domain.User{}, err
+		}
 	}
-	return u.repoImpl.UpdateUser(id, user)
+
+	return u.repoImpl.GetUserById(ctx, id)
 }
 
-func (u userUseCase) LoginUser(emailid string, password string) (int, error) {
+func (u userUseCase) LoginUser(ctx context.Context, emailid string, password string) (int, error) {
 	// TODO
-	userId, err := u.repoImpl.GetUserId(emailid)
+	userId, err := u.repoImpl.GetUserId(ctx, emailid)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		// don't reveal which emails are registered
+		return userId, domain.ErrInvalidCredentials
+	}
 
 	if err != nil {
-		return userId, errors.ErrUnsupported
+		return userId, err
 	}
 
-	user, _ := u.repoImpl.GetUserById(userId)
+	user, err := u.repoImpl.GetUserById(ctx, userId)
+
+	if err != nil {
+		return userId, err
+	}
 
 	if !checkPassword(password, user.HashedPassword) {
-		return userId, errors.ErrUnsupported
+		return userId, domain.ErrInvalidCredentials
+	}
+
+	if user.Suspended {
+		return userId, domain.ErrAccountSuspended
 	}
 
-	return userId, err
+	return userId, nil
 }
 
 func checkPassword(providedPassword string, hashedPassword []byte) bool {
@@ -82,16 +180,62 @@ func checkPassword(providedPassword string, hashedPassword []byte) bool {
 	return err == nil
 }
 
-func (u userUseCase) GetUserById(id int) (domain.User, error) {
-	return u.repoImpl.GetUserById(id)
+func (u userUseCase) GetUserById(ctx context.Context, id int) (domain.User, error) {
+	return u.repoImpl.GetUserById(ctx, id)
 }
 
-func (u userUseCase) PostTweet(body string, author_id int) (domain.Tweet, error) {
+func (u userUseCase) PostTweet(ctx context.Context, draft domain.TweetDraft, author_id int) (domain.Tweet, error) {
 	// business logic here
+	body := draft.Body
+	kind := draft.Kind
+
+	if kind == "" {
+		kind = domain.TweetKindOriginal
+	}
+
+	tweet := domain.Tweet{AuthorId: author_id, Kind: kind}
+
+	switch kind {
+	case domain.TweetKindOriginal:
+		// nothing to reference
+	case domain.TweetKindRetweet, domain.TweetKindQuote:
+		referenced, err := u.referencedTweet(ctx, draft.ReferencedTweetId)
+
+		if err != nil {
+			return domain.Tweet{}, err
+		}
+
+		tweet.ReferencedTweetId = referenced.TweetId
+	default:
+		return domain.Tweet{}, domain.ErrInvalidTweetKind
+	}
+
+	if kind == domain.TweetKindRetweet && body != "" {
+		return domain.Tweet{}, domain.ErrRetweetWithBody
+	}
+
+	parentAuthorId := 0
+
+	if draft.InReplyTo != 0 {
+		if kind == domain.TweetKindRetweet {
+			return domain.Tweet{}, domain.ErrRetweetReply
+		}
+
+		// replying to a retweet replies to the tweet it reposts
+		parent, err := u.referencedTweet(ctx, draft.InReplyTo)
+
+		if err != nil {
+			return domain.Tweet{}, err
+		}
+
+		tweet.InReplyTo = parent.TweetId
+		tweet.ConversationId = parent.ConversationId
+		parentAuthorId = parent.AuthorId
+	}
 
 	// check for validity
 	if len(body) > 140 {
-		return domain.Tweet{}, errors.New("tweet too long")
+		return domain.Tweet{}, domain.ErrTweetTooLong
 
 	}
 
@@ -114,49 +258,115 @@ func (u userUseCase) PostTweet(body string, author_id int) (domain.Tweet, error)
 		body = re.ReplaceAllString(input, "****")
 	}
 
-	tweet := domain.Tweet{Body: body, AuthorId: author_id}
-	return u.repoImpl.SaveTweet(tweet)
-}
+	now := u.clock.Now()
+	tweet.Body = body
+	tweet.Hashtags = extractHashtags(body)
+	tweet.CreatedAt = now
+	tweet.UpdatedAt = now
+	savedTweet, err := u.repoImpl.SaveTweet(ctx, tweet)
+
+	if err != nil {
+		return savedTweet, err
+	}
+
+	if err := u.timeline.TweetPosted(ctx, savedTweet); err != nil {
+		log.Printf("Couldn't fan out tweet %d: %s", savedTweet.TweetId, err)
+	}
 
-func (u userUseCase) GetTweetById(id int) (domain.Tweet, error) {
-	return u.repoImpl.GetTweetById(id)
+	if err := u.search.Index(ctx, savedTweet); err != nil {
+		log.Printf("Couldn't index tweet %d: %s", savedTweet.TweetId, err)
+	}
+
+	u.notifyTweetPosted(ctx, savedTweet, parentAuthorId)
+
+	return savedTweet, nil
 }
 
-func (u userUseCase) DeleteTweet(tweetId int, author_id int) error {
-	repoTweet, err := u.repoImpl.GetTweetById(tweetId)
+// referencedTweet looks up the tweet a retweet, quote or reply points at.
+// Pointing at a retweet points at the tweet it reposts instead.
+func (u userUseCase) referencedTweet(ctx context.Context, referencedTweetId int) (domain.Tweet, error) {
+	if referencedTweetId == 0 {
+		return domain.Tweet{}, domain.ErrMissingReference
+	}
+
+	referenced, err := u.repoImpl.GetTweetById(ctx, referencedTweetId)
 
 	if err != nil {
-		return err
+		return domain.Tweet{}, err
 	}
 
-	if repoTweet.AuthorId != author_id {
-		return errors.ErrUnsupported
+	if referenced.Kind == domain.TweetKindRetweet {
+		return u.repoImpl.GetTweetById(ctx, referenced.ReferencedTweetId)
 	}
 
-	return nil
+	return referenced, nil
 }
 
-func (u userUseCase) GetAllTweets() ([]domain.Tweet, error) {
-	return u.repoImpl.FetchAllTweets()
+func (u userUseCase) GetTweetById(ctx context.Context, id int) (domain.Tweet, error) {
+	return u.repoImpl.GetTweetById(ctx, id)
 }
 
-func (u userUseCase) GetAuthorTweets(author_id int) ([]domain.Tweet, error) {
+// DeleteTweet deletes a tweet of the actor's own. Moderators delete other
+// users' tweets with RemoveTweet.
+func (u userUseCase) DeleteTweet(ctx context.Context, tweetId int, actorId int) error {
+	repoTweet, err := u.repoImpl.GetTweetById(ctx, tweetId)
+
+	if err != nil {
+		return err
+	}
 
-	if _, err := u.repoImpl.GetUserById(author_id); err != nil {
-		return nil, errors.ErrUnsupported
+	if repoTweet.AuthorId != actorId {
+		return domain.ErrNotTweetAuthor
 	}
-	return u.repoImpl.FetchAuthorTweets(author_id)
-}
 
-func (u userUseCase) StoreRefreshToken(token string) bool {
-	return u.repoImpl.CreateToken(token)
+	return u.deleteTweet(ctx, repoTweet)
 }
 
-func (u userUseCase) RevokeRefreshToken(token string) bool {
+func (u userUseCase) deleteTweet(ctx context.Context, tweet domain.Tweet) error {
+	if err := u.repoImpl.DeleteTweet(ctx, tweet); err != nil {
+		return err
+	}
+
+	if err := u.search.Remove(ctx, tweet.TweetId); err != nil {
+		log.Printf("Couldn't remove tweet %d from the search index: %s", tweet.TweetId, err)
+	}
 
-	return u.repoImpl.UpdateToken(token, true)
+	return nil
 }
 
-func (u userUseCase) IsRefreshTokenRevoked(token string) bool {
-	return u.repoImpl.ReadToken(token)
+func (u userUseCase) ListTweets(ctx context.Context, filter domain.TweetFilter, page domain.PageRequest) (domain.TweetPage, error) {
+
+	if filter.AuthorId != 0 {
+		if _, err := u.repoImpl.GetUserById(ctx, filter.AuthorId); err != nil {
+			return domain.TweetPage{}, err
+		}
+	}
+
+	limit, err := pageLimit(page)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	afterId, err := decodeCursor(page.Cursor)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	// fetch one extra tweet to find out whether there is a next page
+	tweets, err := u.repoImpl.ListTweets(ctx, filter, afterId, limit+1)
+
+	if err != nil {
+		return domain.TweetPage{}, err
+	}
+
+	result := domain.TweetPage{Tweets: tweets}
+
+	if len(tweets) > limit {
+		result.Tweets = tweets[:limit]
+		result.NextCursor = encodeCursor(tweets[limit-1].TweetId)
+	}
+
+	return result, nil
 }
@@ -0,0 +1,201 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestUpdateUser(t *testing.T) {
+	email, padded, password, empty, blank := "new@example.com", " new@example.com ", "new password", "", "  "
+
+	tests := []struct {
+		name   string
+		update domain.UserUpdate
+		// wantErr is the error kind expected, nil for success
+		wantErr error
+		// wantEmail and wantPassword are what the user logs in with afterwards
+		wantEmail    string
+		wantPassword string
+	}{
+		// fields left out of the request are kept
+		{"NothingTakesNoPassword", domain.UserUpdate{}, nil, "old@example.com", "secret"},
+		{"EmailKeepsPassword", domain.UserUpdate{Email: &email, CurrentPassword: "secret"}, nil, email, "secret"},
+		{"PasswordKeepsEmail", domain.UserUpdate{Password: &password, CurrentPassword: "secret"}, nil, "old@example.com", password},
+		{"Both", domain.UserUpdate{Email: &email, Password: &password, CurrentPassword: "secret"}, nil, email, password},
+		{"EmailTrimmed", domain.UserUpdate{Email: &padded, CurrentPassword: "secret"}, nil, email, "secret"},
+		// fields sent empty are refused rather than cleared
+		{"EmptyEmail", domain.UserUpdate{Email: &empty, CurrentPassword: "secret"}, domain.ErrValidation, "old@example.com", "secret"},
+		{"BlankEmail", domain.UserUpdate{Email: &blank, CurrentPassword: "secret"}, domain.ErrValidation, "old@example.com", "secret"},
+		{"EmptyPassword", domain.UserUpdate{Password: &empty, CurrentPassword: "secret"}, domain.ErrValidation, "old@example.com", "secret"},
+		{"EmptyPasswordKeepsEmail", domain.UserUpdate{Email: &email, Password: &empty, CurrentPassword: "secret"}, domain.ErrValidation, "old@example.com", "secret"},
+		// any change takes the current password
+		{"EmailWithoutCurrentPassword", domain.UserUpdate{Email: &email}, domain.ErrValidation, "old@example.com", "secret"},
+		{"PasswordWithoutCurrentPassword", domain.UserUpdate{Password: &password}, domain.ErrValidation, "old@example.com", "secret"},
+		{"WrongCurrentPassword", domain.UserUpdate{Email: &email, CurrentPassword: "guess"}, domain.ErrForbidden, "old@example.com", "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			user, err := env.uc.CreateUser(ctx, "old@example.com", "secret", domain.Profile{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			updated, err := env.uc.UpdateUser(ctx, user.ID, "", tt.update)
+
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && updated.Email != tt.wantEmail {
+				t.Fatalf("expected the updated user to have %s, got %s", tt.wantEmail, updated.Email)
+			}
+
+			if userId, err := env.uc.LoginUser(ctx, tt.wantEmail, tt.wantPassword); err != nil || userId != user.ID {
+				t.Fatalf("expected to log in as %s with %q, got %d %v", tt.wantEmail, tt.wantPassword, userId, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserSessions(t *testing.T) {
+	email, password := "moved@example.com", "new password"
+
+	tests := []struct {
+		name   string
+		update domain.UserUpdate
+		// wantErr is the error kind expected, nil for success
+		wantErr error
+		// wantSessions are the sessions left, by the order they were opened
+		wantSessions []int
+	}{
+		{"PasswordChangeLogsOutOthers", domain.UserUpdate{Password: &password, CurrentPassword: "secret"}, nil, []int{1}},
+		{"EmailChangeKeepsSessions", domain.UserUpdate{Email: &email, CurrentPassword: "secret"}, nil, []int{0, 1, 2}},
+		{"WrongPasswordChangesNothing", domain.UserUpdate{Password: &password, CurrentPassword: "guess"}, domain.ErrForbidden, []int{0, 1, 2}},
+		{"MissingPasswordChangesNothing", domain.UserUpdate{Password: &password}, domain.ErrValidation, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv()
+			user, err := env.uc.CreateUser(ctx, "sessions@example.com", "secret", domain.Profile{})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sessionIds := make([]string, 3)
+
+			for i := range sessionIds {
+				token := string(rune('a' + i))
+				sessionIds[i], _ = env.uc.IssueRefreshToken(ctx, user.ID, token, env.clock.Now().Add(time.Hour), domain.Client{})
+			}
+
+			// the update comes from the second session
+			_, err = env.uc.UpdateUser(ctx, user.ID, sessionIds[1], tt.update)
+
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			want := make([]string, 0, len(tt.wantSessions))
+			for _, i := range tt.wantSessions {
+				want = append(want, sessionIds[i])
+			}
+
+			assertSessions(t, env, user.ID, want)
+
+			for i, sessionId := range sessionIds {
+				live := env.uc.CheckSession(ctx, user.ID, sessionId) == nil
+
+				if live != contains(want, sessionId) {
+					t.Fatalf("session %d: CheckSession says live=%v", i, live)
+				}
+			}
+		})
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+	owner, stranger := mustUser(t, env.repo, "owner"), mustUser(t, env.repo, "stranger")
+	expiresAt := env.clock.Now().Add(time.Hour)
+
+	kept, _ := env.uc.IssueRefreshToken(ctx, owner.ID, "kept", expiresAt, domain.Client{})
+	revoked, _ := env.uc.IssueRefreshToken(ctx, owner.ID, "revoked", expiresAt, domain.Client{})
+
+	// nobody logs out someone else's session
+	if err := env.uc.RevokeSession(ctx, stranger.ID, revoked); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected another user's session to be not found, got %v", err)
+	}
+
+	if err := env.uc.CheckSession(ctx, stranger.ID, kept); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("a session passed for another user's: %v", err)
+	}
+
+	if err := env.uc.RevokeSession(ctx, owner.ID, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	assertSessions(t, env, owner.ID, []string{kept})
+
+	if _, err := env.uc.RotateRefreshToken(ctx, "revoked", "next", expiresAt, domain.Client{}); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("a logged out session refreshed: %v", err)
+	}
+
+	// sessions end when their refresh token expires
+	env.clock.Advance(time.Hour)
+
+	if err := env.uc.CheckSession(ctx, owner.ID, kept); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("an expired session passed: %v", err)
+	}
+}
+
+func assertSessions(t *testing.T, env testEnv, userId int, want []string) {
+	t.Helper()
+
+	sessions, err := env.uc.ListSessions(context.Background(), userId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		got = append(got, session.Id)
+	}
+
+	want = append([]string(nil), want...)
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("expected sessions %v, got %v", want, got)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("expected sessions %v, got %v", want, got)
+		}
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+// RemoveTweet deletes anyone's tweet on behalf of a moderator or admin
+func (u *UserHttpHandler) RemoveTweet(w http.ResponseWriter, r *http.Request) {
+	tweetId, err := strconv.Atoi(chi.URLParam(r, "tweetId"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	request := AdminActionRequestDTO{}
+
+	if err := decodeOptionalBody(r, &request); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	if err := u.uuc.RemoveTweet(r.Context(), requestPrincipal(r).UserId, tweetId, request.Reason); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, "tweet removed")
+}
+
+// SuspendUser locks the user in the path out
+func (u *UserHttpHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	u.changeSuspension(w, r, u.uuc.SuspendUser)
+}
+
+// UnsuspendUser lets the user in the path back in
+func (u *UserHttpHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	u.changeSuspension(w, r, u.uuc.UnsuspendUser)
+}
+
+func (u *UserHttpHandler) changeSuspension(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, actorId int, userId int, reason string) (domain.User, error),
+) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	request := AdminActionRequestDTO{}
+
+	if err := decodeOptionalBody(r, &request); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	user, err := change(r.Context(), requestPrincipal(r).UserId, userId, request.Reason)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toAdminUserDTO(user))
+}
+
+// GrantRole gives the user in the path a role
+func (u *UserHttpHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	request := RoleRequestDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
+	}
+
+	user, err := u.uuc.GrantRole(r.Context(), requestPrincipal(r).UserId, userId, domain.Role(request.Role), request.Reason)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toAdminUserDTO(user))
+}
+
+// GetAuditLog pages through the audit log, newest first
+func (u *UserHttpHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	auditPage, err := u.uuc.ListAuditLog(r.Context(), requestPrincipal(r).UserId, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := AuditLogResponseDTO{
+		Entries:    make([]AuditEntryDTO, 0, len(auditPage.Entries)),
+		NextCursor: auditPage.NextCursor,
+	}
+
+	for _, entry := range auditPage.Entries {
+		response.Entries = append(response.Entries, AuditEntryDTO{
+			ID:           entry.Id,
+			ActorID:      entry.ActorId,
+			Action:       string(entry.Action),
+			TargetUserID: entry.TargetUserId,
+			TweetID:      entry.TweetId,
+			Detail:       entry.Detail,
+			Reason:       entry.Reason,
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func toAdminUserDTO(user domain.User) AdminUserDTO {
+	return AdminUserDTO{
+		UserSummaryDTO: userSummary(user),
+		Role:           string(user.Role),
+		Suspended:      user.Suspended,
+	}
+}
+
+// decodeOptionalBody decodes a JSON body that may be left out altogether
+func decodeOptionalBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return errMalformedBody
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// tweetRoutes routes tweet deletion the way main.go does
+func (s testServer) tweetRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(s.handler.RequireAuth)
+
+	r.With(s.handler.RequireScope(domain.ScopeTweetsWrite)).Delete("/tweets/{tweetId}", s.handler.DeleteTweet)
+	r.With(s.handler.RequireLogin).Delete("/admin/tweets/{tweetId}", s.handler.RemoveTweet)
+
+	return r
+}
+
+func TestDeleteTweetModeration(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	author := s.user(t, "author@example.com")
+	moderator := s.user(t, "moderator@example.com")
+
+	if err := s.repo.UpdateUserRole(ctx, moderator.ID, domain.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+
+	authorLogin, _ := s.login(t, author.ID)
+	moderatorLogin, _ := s.login(t, moderator.ID)
+	_, authorToken, err := s.uc.CreateAccessToken(ctx, author.ID, "bot", []domain.Scope{domain.ScopeTweetsWrite})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, moderatorToken, err := s.uc.CreateAccessToken(ctx, moderator.ID, "bot", []domain.Scope{domain.ScopeTweetsWrite})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		token    string
+		wantCode int
+	}{
+		{"AuthorWithToken", "/tweets/", authorToken, http.StatusOK},
+		{"AuthorLogin", "/tweets/", authorLogin, http.StatusOK},
+		// moderation only goes through the admin endpoint, from a login
+		{"ModeratorTokenOnTweets", "/tweets/", moderatorToken, http.StatusForbidden},
+		{"ModeratorLoginOnTweets", "/tweets/", moderatorLogin, http.StatusForbidden},
+		{"ModeratorTokenOnAdmin", "/admin/tweets/", moderatorToken, http.StatusForbidden},
+		{"ModeratorLoginOnAdmin", "/admin/tweets/", moderatorLogin, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tweet, err := s.repo.SaveTweet(ctx, domain.Tweet{Body: "hello", AuthorId: author.ID})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := httptest.NewRequest(http.MethodDelete, tt.path+strconv.Itoa(tweet.TweetId), nil)
+			request.Header.Set("Authorization", "Bearer "+tt.token)
+			response := httptest.NewRecorder()
+			s.tweetRoutes().ServeHTTP(response, request)
+
+			if response.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d %q", tt.wantCode, response.Code, response.Body.String())
+			}
+
+			_, err = s.repo.GetTweetById(ctx, tweet.TweetId)
+
+			if deleted := err != nil; deleted != (tt.wantCode == http.StatusOK) {
+				t.Fatalf("expected the tweet to be deleted: %v, got %v", tt.wantCode == http.StatusOK, err)
+			}
+		})
+	}
+}
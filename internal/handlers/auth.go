@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// The issuers tell access tokens and refresh tokens apart, neither is
+// accepted where the other is expected
+const (
+	accessTokenIssuer  = "chirpy-access"
+	refreshTokenIssuer = "chirpy-refresh"
+)
+
+var (
+	errMissingCredentials   = fmt.Errorf("%w: missing authorization header", domain.ErrUnauthenticated)
+	errMalformedCredentials = fmt.Errorf("%w: malformed authorization header", domain.ErrUnauthenticated)
+	errInvalidToken         = fmt.Errorf("%w: invalid token", domain.ErrUnauthenticated)
+	errLoginRequired        = fmt.Errorf("%w: personal access tokens can't manage the account, log in instead", domain.ErrForbidden)
+)
+
+// Principal is who a request is made on behalf of, as proven by its token:
+// the access token of a login or a personal access token
+type Principal struct {
+	UserId int
+
+	// SessionId is the session the access token was issued for, empty for
+	// personal access tokens and tokens from before sessions
+	SessionId string
+
+	// AccessToken is the personal access token the request came with, if any
+	AccessToken *domain.AccessToken
+}
+
+// Can tells whether the principal may do what the scope covers. Logins may
+// do everything, personal access tokens what they were granted.
+func (p Principal) Can(scope domain.Scope) bool {
+	return p.AccessToken == nil || p.AccessToken.HasScope(scope)
+}
+
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the principal the authentication middleware
+// put in the context, if any
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+
+// RequireAuth rejects requests without a valid access token and hands the
+// principal it proves down to the handler in the request context
+func (u *UserHttpHandler) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := u.authenticate(r)
+
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithDomainError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal)))
+	})
+}
+
+// RequireScope rejects requests made with a personal access token that
+// wasn't granted the scope. It goes after RequireAuth.
+func (u *UserHttpHandler) RequireScope(scope domain.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !requestPrincipal(r).Can(scope) {
+				respondWithDomainError(w, fmt.Errorf("%w: the token lacks the %s scope", domain.ErrForbidden, scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireLogin rejects requests made with a personal access token, the
+// account itself is only managed from a login. It goes after RequireAuth.
+func (u *UserHttpHandler) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestPrincipal(r).AccessToken != nil {
+			respondWithDomainError(w, errLoginRequired)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OptionalAuth lets every request through, with the principal in the context
+// when it carries a valid access token. Endpoints that anyone may read use it
+// to tailor the response to a logged in viewer.
+func (u *UserHttpHandler) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, err := u.authenticate(r); err == nil {
+			r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestPrincipal returns the principal of a request that went through
+// RequireAuth, or the zero principal for an anonymous one
+func requestPrincipal(r *http.Request) Principal {
+	principal, _ := PrincipalFromContext(r.Context())
+	return principal
+}
+
+// viewerId returns the id of the authenticated user, or 0 for anonymous
+// requests to endpoints that don't require a login
+func viewerId(r *http.Request) int {
+	return requestPrincipal(r).UserId
+}
+
+// authenticate checks the access token or personal access token in the
+// Authorization header
+func (u *UserHttpHandler) authenticate(r *http.Request) (Principal, error) {
+	tokenString, err := authorizationCredentials(r, "Bearer")
+
+	if err != nil {
+		return Principal{}, err
+	}
+
+	var principal Principal
+
+	if strings.HasPrefix(tokenString, domain.AccessTokenPrefix) {
+		accessToken, err := u.uuc.AuthenticateAccessToken(r.Context(), tokenString)
+
+		if err != nil {
+			return Principal{}, err
+		}
+
+		principal = Principal{UserId: accessToken.UserId, AccessToken: &accessToken}
+	} else {
+		principal, err = u.parseToken(tokenString, accessTokenIssuer)
+
+		if err != nil {
+			return Principal{}, err
+		}
+
+		// an access token is good only as long as its session, logging the
+		// session out ends it at once. Tokens from before sessions have none.
+		if principal.SessionId != "" {
+			if err := u.uuc.CheckSession(r.Context(), principal.UserId, principal.SessionId); err != nil {
+				return Principal{}, err
+			}
+		}
+	}
+
+	// suspended users are locked out at once, whatever tokens they still hold
+	_, err = u.uuc.GetActiveUser(r.Context(), principal.UserId)
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return Principal{}, fmt.Errorf("%w: the token's user doesn't exist", domain.ErrUnauthenticated)
+	}
+
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return principal, nil
+}
+
+// parseToken verifies a token chirpy issued and checks it is of the kind
+// the issuer names
+func (u *UserHttpHandler) parseToken(tokenString string, issuer string) (Principal, error) {
+	claims := chirpyClaims{}
+
+	token, err := u.keys.Parse(tokenString, &claims)
+
+	if err != nil || !token.Valid || claims.Issuer != issuer {
+		return Principal{}, errInvalidToken
+	}
+
+	userId, err := strconv.Atoi(claims.Subject)
+
+	if err != nil || userId <= 0 {
+		return Principal{}, errInvalidToken
+	}
+
+	return Principal{UserId: userId, SessionId: claims.SessionId}, nil
+}
+
+// authorizationCredentials returns the credentials of an Authorization header
+// of the form "<scheme> <credentials>". The scheme is case-insensitive.
+func authorizationCredentials(r *http.Request, scheme string) (string, error) {
+	header := r.Header.Get("Authorization")
+
+	if header == "" {
+		return "", errMissingCredentials
+	}
+
+	parts := strings.Fields(header)
+
+	if len(parts) != 2 || !strings.EqualFold(parts[0], scheme) {
+		return "", errMalformedCredentials
+	}
+
+	return parts[1], nil
+}
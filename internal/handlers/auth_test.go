@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+	"github.com/anandh86/chirpy/internal/core/usecases"
+	adapters "github.com/anandh86/chirpy/internal/repositories"
+)
+
+// testServer is a handler wired to the real use cases over in-memory adapters
+type testServer struct {
+	handler *UserHttpHandler
+	uc      ports.IUseCase
+	repo    ports.IRepository
+}
+
+func newTestServer(t *testing.T) testServer {
+	t.Helper()
+
+	repo := adapters.ProvideInMemoryRepo()
+	mailer, err := adapters.ProvideOutboxMailer(t.TempDir())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uc := usecases.ProvideUserUseCase(
+		repo,
+		usecases.ProvideFanOutOnReadTimeline(repo),
+		adapters.ProvideSystemClock(),
+		adapters.ProvideInMemoryNotificationStore(),
+		adapters.ProvideInMemorySearchIndex(),
+		mailer,
+	)
+
+	keys, err := ProvideKeyManager("", "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testServer{handler: ProvideUserHttpHandler(uc, keys), uc: uc, repo: repo}
+}
+
+func (s testServer) user(t *testing.T, email string) domain.User {
+	t.Helper()
+
+	user, err := s.repo.Save(context.Background(), domain.User{Email: email, HashedPassword: []byte("x")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// login starts a session for the user the way LoginUser does and returns
+// its access token and session id
+func (s testServer) login(t *testing.T, userId int) (string, string) {
+	t.Helper()
+
+	refreshToken, expiresAt, err := s.handler.generateRefreshToken(userId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionId, err := s.uc.IssueRefreshToken(context.Background(), userId, refreshToken, expiresAt, domain.Client{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessToken, err := s.handler.generateAccessToken(userId, sessionId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return accessToken, sessionId
+}
+
+// serve sends a request with the Authorization header through the middleware
+// to a handler that answers with the id of the principal it was given
+func serve(authorization string, middleware ...func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := PrincipalFromContext(r.Context()); ok {
+			w.Write([]byte(strconv.Itoa(principal.UserId)))
+		}
+	})
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestRequireAuth(t *testing.T) {
+	s := newTestServer(t)
+	user := s.user(t, "auth@example.com")
+	accessToken, _ := s.login(t, user.ID)
+	loggedOutToken, loggedOutSession := s.login(t, user.ID)
+
+	if err := s.uc.RevokeSession(context.Background(), user.ID, loggedOutSession); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshToken, _, _ := s.handler.generateRefreshToken(user.ID)
+	expiredToken, _ := s.handler.createJWTToken(user.ID, "", time.Now().Add(-time.Minute), accessTokenIssuer)
+	unknownUserToken, _ := s.handler.createJWTToken(42, "", time.Now().Add(time.Hour), accessTokenIssuer)
+
+	tests := []struct {
+		name          string
+		authorization string
+		// wantUserId is the principal handed down, 0 when the request is rejected
+		wantUserId int
+	}{
+		{"Valid", "Bearer " + accessToken, user.ID},
+		{"SchemeIgnoresCase", "bEaReR " + accessToken, user.ID},
+		{"Missing", "", 0},
+		{"SchemeOnly", "Bearer", 0},
+		{"WrongScheme", "Basic " + accessToken, 0},
+		{"ApiKeyScheme", "ApiKey " + accessToken, 0},
+		{"ExtraField", "Bearer " + accessToken + " extra", 0},
+		{"NotAToken", "Bearer not-a-token", 0},
+		{"RefreshTokenAsAccessToken", "Bearer " + refreshToken, 0},
+		{"Expired", "Bearer " + expiredToken, 0},
+		{"UnknownUser", "Bearer " + unknownUserToken, 0},
+		{"LoggedOutSession", "Bearer " + loggedOutToken, 0},
+		{"UnknownPersonalAccessToken", "Bearer " + domain.AccessTokenPrefix + "unknown", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := serve(tt.authorization, s.handler.RequireAuth)
+
+			if tt.wantUserId == 0 {
+				if response.Code != http.StatusUnauthorized || response.Header().Get("WWW-Authenticate") != "Bearer" {
+					t.Fatalf("expected a 401 with a Bearer challenge, got %d %q", response.Code, response.Header().Get("WWW-Authenticate"))
+				}
+				return
+			}
+
+			if response.Code != http.StatusOK || response.Body.String() != strconv.Itoa(tt.wantUserId) {
+				t.Fatalf("expected user %d through, got %d %q", tt.wantUserId, response.Code, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	s := newTestServer(t)
+	user := s.user(t, "optional@example.com")
+	accessToken, _ := s.login(t, user.ID)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantBody      string
+	}{
+		{"Valid", "Bearer " + accessToken, strconv.Itoa(user.ID)},
+		{"Anonymous", "", ""},
+		// a bad token makes the request anonymous rather than failing it
+		{"Malformed", "Bearer", ""},
+		{"Invalid", "Bearer not-a-token", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := serve(tt.authorization, s.handler.OptionalAuth)
+
+			if response.Code != http.StatusOK || response.Body.String() != tt.wantBody {
+				t.Fatalf("expected a 200 with %q, got %d %q", tt.wantBody, response.Code, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	user := s.user(t, "pat@example.com")
+	loginToken, _ := s.login(t, user.ID)
+
+	_, readOnly, err := s.uc.CreateAccessToken(ctx, user.ID, "reader", []domain.Scope{domain.ScopeTweetsRead})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, revokedSecret, err := s.uc.CreateAccessToken(ctx, user.ID, "revoked", []domain.Scope{domain.ScopeTweetsWrite})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.uc.RevokeAccessToken(ctx, user.ID, revoked.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		middleware    []func(http.Handler) http.Handler
+		wantCode      int
+	}{
+		{"InScope", "Bearer " + readOnly, []func(http.Handler) http.Handler{s.handler.RequireAuth, s.handler.RequireScope(domain.ScopeTweetsRead)}, http.StatusOK},
+		{"OutOfScope", "Bearer " + readOnly, []func(http.Handler) http.Handler{s.handler.RequireAuth, s.handler.RequireScope(domain.ScopeTweetsWrite)}, http.StatusForbidden},
+		// a login may do everything its user may
+		{"LoginPassesScope", "Bearer " + loginToken, []func(http.Handler) http.Handler{s.handler.RequireAuth, s.handler.RequireScope(domain.ScopeTweetsWrite)}, http.StatusOK},
+		{"LoginOnlyRejectsToken", "Bearer " + readOnly, []func(http.Handler) http.Handler{s.handler.RequireAuth, s.handler.RequireLogin}, http.StatusForbidden},
+		{"LoginOnlyPassesLogin", "Bearer " + loginToken, []func(http.Handler) http.Handler{s.handler.RequireAuth, s.handler.RequireLogin}, http.StatusOK},
+		{"Revoked", "Bearer " + revokedSecret, []func(http.Handler) http.Handler{s.handler.RequireAuth}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := serve(tt.authorization, tt.middleware...)
+
+			if response.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d %q", tt.wantCode, response.Code, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireAuthSuspendedUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	user := s.user(t, "suspended@example.com")
+
+	// tokens that outlive a suspension, since they aren't tied to a session
+	sessionless, _ := s.handler.createJWTToken(user.ID, "", time.Now().Add(time.Hour), accessTokenIssuer)
+	_, personal, err := s.uc.CreateAccessToken(ctx, user.ID, "bot", []domain.Scope{domain.ScopeTweetsRead})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.repo.UpdateUserSuspension(ctx, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"Sessionless": sessionless, "PersonalAccessToken": personal} {
+		t.Run(name, func(t *testing.T) {
+			if response := serve("Bearer "+token, s.handler.RequireAuth); response.Code != http.StatusForbidden {
+				t.Fatalf("expected a suspended user to get 403, got %d %q", response.Code, response.Body.String())
+			}
+		})
+	}
+}
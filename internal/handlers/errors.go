@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// Failures detected by the handlers themselves, expressed in the core's
+// error kinds so they go through the same mapping
+var (
+	errMalformedBody     = fmt.Errorf("%w: malformed json body", domain.ErrValidation)
+	errInvalidParameters = fmt.Errorf("%w: invalid parameters", domain.ErrValidation)
+)
+
+// respondWithDomainError maps an error coming out of the core onto a status
+// code, so every endpoint reports the same failure the same way
+func respondWithDomainError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrValidation):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrUnauthenticated):
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, domain.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// the client went away or the request deadline passed
+		respondWithError(w, http.StatusServiceUnavailable, "request cancelled")
+	default:
+		log.Printf("Unexpected error: %s", err)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
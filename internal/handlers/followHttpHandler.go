@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+func (u *UserHttpHandler) FollowUser(w http.ResponseWriter, r *http.Request) {
+	u.changeFollow(w, r, u.uuc.FollowUser)
+}
+
+func (u *UserHttpHandler) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	u.changeFollow(w, r, u.uuc.UnfollowUser)
+}
+
+func (u *UserHttpHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	u.listFollowGraph(w, r, u.uuc.ListFollowers, func(stats domain.FollowStats) int {
+		return stats.Followers
+	})
+}
+
+func (u *UserHttpHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	u.listFollowGraph(w, r, u.uuc.ListFollowing, func(stats domain.FollowStats) int {
+		return stats.Following
+	})
+}
+
+// changeFollow runs a follow or unfollow of the user in the path on behalf
+// of the authenticated user and responds with the target's new counts
+func (u *UserHttpHandler) changeFollow(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, followerId int, followeeId int) error,
+) {
+	followerId := requestPrincipal(r).UserId
+
+	followeeId, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	if err := change(r.Context(), followerId, followeeId); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	stats, err := u.uuc.GetFollowStats(r.Context(), followeeId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, FollowStatsResponseDTO{
+		UserID:         followeeId,
+		FollowersCount: stats.Followers,
+		FollowingCount: stats.Following,
+	})
+}
+
+// listFollowGraph pages through one side of the follow graph of the user in the path
+func (u *UserHttpHandler) listFollowGraph(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(ctx context.Context, userId int, page domain.PageRequest) (domain.UserPage, error),
+	count func(stats domain.FollowStats) int,
+) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	userPage, err := list(r.Context(), userId, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	stats, err := u.uuc.GetFollowStats(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := UserPageResponseDTO{
+		Users:      make([]UserSummaryDTO, 0, len(userPage.Users)),
+		Count:      count(stats),
+		NextCursor: userPage.NextCursor,
+	}
+
+	for _, user := range userPage.Users {
+		response.Users = append(response.Users, userSummary(user))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
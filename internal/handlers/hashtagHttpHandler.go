@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// GetHashtagTweets lists the tweets tagged with the hashtag in the path, newest first
+func (u *UserHttpHandler) GetHashtagTweets(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	tweetPage, err := u.uuc.ListHashtagTweets(r.Context(), chi.URLParam(r, "tag"), page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithTweetPage(w, r, viewerId(r), tweetPage)
+}
+
+// GetTrends lists the hashtags trending right now, hottest first
+func (u *UserHttpHandler) GetTrends(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil {
+			respondWithDomainError(w, errInvalidParameters)
+			return
+		}
+		limit = parsed
+	}
+
+	trends, err := u.uuc.GetTrends(r.Context(), limit)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := TrendsResponseDTO{Trends: make([]TrendDTO, 0, len(trends))}
+
+	for _, trend := range trends {
+		response.Trends = append(response.Trends, TrendDTO{
+			Tag:        trend.Tag,
+			Score:      math.Round(trend.Score*1000) / 1000,
+			TweetCount: trend.Uses,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -1,22 +1,252 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
 type UserResponseWithTokenDTO struct {
-	Email        string `json:"email"`
-	ID           int    `json:"id"`
-	IsChirpyRed  bool   `json:"is_chirpy_red"`
-	AccessToken  string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
+	Email       string `json:"email"`
+	ID          int    `json:"id"`
+	IsChirpyRed bool   `json:"is_chirpy_red"`
+	ProfileDTO
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
 }
 
 type UserResponseDTO struct {
 	Email       string `json:"email"`
 	ID          int    `json:"id"`
 	IsChirpyRed bool   `json:"is_chirpy_red"`
+	ProfileDTO
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProfileDTO struct {
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	Location    string `json:"location"`
+}
+
+// UserSummaryDTO is what other users get to see about a user
+type UserSummaryDTO struct {
+	ID          int  `json:"id"`
+	IsChirpyRed bool `json:"is_chirpy_red"`
+	ProfileDTO
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProfileResponseDTO is a user's public profile page
+type ProfileResponseDTO struct {
+	UserSummaryDTO
+	FollowersCount int `json:"followers_count"`
+	FollowingCount int `json:"following_count"`
+}
+
+type UserPageResponseDTO struct {
+	Users      []UserSummaryDTO `json:"users"`
+	Count      int              `json:"count"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type FollowStatsResponseDTO struct {
+	UserID         int `json:"user_id"`
+	FollowersCount int `json:"followers_count"`
+	FollowingCount int `json:"following_count"`
 }
 
 type UserRequestDTO struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	ProfileDTO
+}
+
+// UserUpdateRequestDTO holds the fields to change, absent ones are left alone
+type UserUpdateRequestDTO struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// ForgotPasswordRequestDTO asks for a password reset token by email
+type ForgotPasswordRequestDTO struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequestDTO sets a new password with an emailed reset token
+type ResetPasswordRequestDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type TweetRequestDTO struct {
+	Body              string           `json:"body"`
+	Kind              domain.TweetKind `json:"kind"`
+	ReferencedTweetId int              `json:"referenced_tweet_id"`
+	InReplyTo         int              `json:"in_reply_to"`
+}
+
+// TweetResponseDTO is a tweet along with its engagement as seen by the requester
+type TweetResponseDTO struct {
+	domain.Tweet
+	LikeCount    int  `json:"like_count"`
+	Liked        bool `json:"liked"`
+	RetweetCount int  `json:"retweet_count"`
+	Retweeted    bool `json:"retweeted"`
+	// ReferencedTweet is the tweet a retweet or quote reposts, left out once
+	// it's deleted
+	ReferencedTweet *TweetResponseDTO `json:"referenced_tweet,omitempty"`
+}
+
+type TweetPageResponseDTO struct {
+	Tweets     []TweetResponseDTO `json:"tweets"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// ThreadNodeResponseDTO is a tweet of a conversation with the first page of its replies
+type ThreadNodeResponseDTO struct {
+	TweetResponseDTO
+	ReplyCount int                     `json:"reply_count"`
+	Replies    []ThreadNodeResponseDTO `json:"replies"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+type ThreadResponseDTO struct {
+	Ancestors []TweetResponseDTO `json:"ancestors"`
+	ThreadNodeResponseDTO
+}
+
+type LikeStatsResponseDTO struct {
+	TweetID   int  `json:"tweet_id"`
+	LikeCount int  `json:"like_count"`
+	Liked     bool `json:"liked"`
+}
+
+type TrendDTO struct {
+	Tag        string  `json:"tag"`
+	Score      float64 `json:"score"`
+	TweetCount int     `json:"tweet_count"`
+}
+
+type TrendsResponseDTO struct {
+	Trends []TrendDTO `json:"trends"`
+}
+
+type NotificationDTO struct {
+	ID        int                     `json:"id"`
+	Kind      domain.NotificationKind `json:"kind"`
+	ActorID   int                     `json:"actor_id"`
+	TweetID   int                     `json:"tweet_id,omitempty"`
+	Read      bool                    `json:"read"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+type NotificationPageResponseDTO struct {
+	Notifications []NotificationDTO `json:"notifications"`
+	UnreadCount   int               `json:"unread_count"`
+	NextCursor    string            `json:"next_cursor,omitempty"`
+}
+
+// MarkNotificationsReadRequestDTO lists the notifications to mark read, all
+// of them when IDs is empty
+type MarkNotificationsReadRequestDTO struct {
+	IDs []int `json:"ids"`
+}
+
+type UnreadNotificationsResponseDTO struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+type AdminActionRequestDTO struct {
+	Reason string `json:"reason"`
+}
+
+type RoleRequestDTO struct {
+	Role   string `json:"role"`
+	Reason string `json:"reason"`
+}
+
+// AdminUserDTO is a user as admins see them
+type AdminUserDTO struct {
+	UserSummaryDTO
+	Role      string `json:"role"`
+	Suspended bool   `json:"suspended"`
+}
+
+type AuditEntryDTO struct {
+	ID           int       `json:"id"`
+	ActorID      int       `json:"actor_id"`
+	Action       string    `json:"action"`
+	TargetUserID int       `json:"target_user_id"`
+	TweetID      int       `json:"tweet_id,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type AuditLogResponseDTO struct {
+	Entries    []AuditEntryDTO `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Current is whether the request was made from this session
+	Current bool `json:"current"`
+}
+
+type SessionsResponseDTO struct {
+	Sessions []SessionDTO `json:"sessions"`
+}
+
+type AccessTokenRequestDTO struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type AccessTokenDTO struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccessTokenCreatedDTO carries the secret of a new token, the only time it is shown
+type AccessTokenCreatedDTO struct {
+	AccessTokenDTO
+	Token string `json:"token"`
+}
+
+type AccessTokensResponseDTO struct {
+	Tokens []AccessTokenDTO `json:"tokens"`
+}
+
+// JWKDTO is a public key in JSON Web Key form, N and E are set for RSA
+// keys, Crv and X for Ed25519 ones
+type JWKDTO struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSetDTO struct {
+	Keys []JWKDTO `json:"keys"`
 }
 
 type Data struct {
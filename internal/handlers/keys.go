@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA key the key manager accepts
+const minRSAKeyBits = 2048
+
+var errUnknownKey = errors.New("token signed with an unknown key")
+
+// KeyManager signs the tokens chirpy issues and picks the key to verify a
+// token with by its kid header. One key signs, every key it holds verifies,
+// so a key that was rotated out keeps verifying its tokens until they expire.
+type KeyManager struct {
+	signingKid string
+	signingKey any
+	method     jwt.SigningMethod
+	keys       map[string]verificationKey
+
+	// legacySecret verifies the HS256 tokens without a kid that were signed
+	// before asymmetric keys, and signs new ones when there are no keys
+	legacySecret []byte
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// ProvideKeyManager loads the keys in dir, one PEM file per key named after
+// its kid. Private keys (PKCS#8, or PKCS#1 for RSA) can sign, public keys
+// only verify. The key signingKid signs, it can be left empty when dir holds
+// a single private key. legacySecret is the old HS256 secret, if any.
+//
+// Without dir, tokens are signed HS256 with legacySecret as before, or with
+// a throwaway Ed25519 key when there is no secret either.
+func ProvideKeyManager(dir string, signingKid string, legacySecret string) (*KeyManager, error) {
+	k := &KeyManager{keys: make(map[string]verificationKey)}
+
+	if legacySecret != "" {
+		k.legacySecret = []byte(legacySecret)
+	}
+
+	if dir != "" {
+		return k, k.load(dir, signingKid)
+	}
+
+	if k.legacySecret != nil {
+		log.Printf("Signing tokens HS256 with JWT_SECRET, set JWT_KEYS_DIR to sign with asymmetric keys\n")
+		k.signingKey, k.method = k.legacySecret, jwt.SigningMethodHS256
+		return k, nil
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Signing tokens with a throwaway key, they won't survive a restart. Set JWT_KEYS_DIR to keep them.\n")
+	k.signingKid = "ephemeral-" + hex.EncodeToString(kid)
+	k.signingKey, k.method = private, jwt.SigningMethodEdDSA
+	k.keys[k.signingKid] = verificationKey{method: jwt.SigningMethodEdDSA, public: public}
+	return k, nil
+}
+
+func (k *KeyManager) load(dir string, signingKid string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+
+	if err != nil {
+		return err
+	}
+
+	privateKeys := make(map[string]any)
+
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := readPEMKey(path)
+
+		if err != nil {
+			return fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		public := key
+		if signer, ok := key.(crypto.Signer); ok {
+			privateKeys[kid] = key
+			public = signer.Public()
+		}
+
+		switch public := public.(type) {
+		case *rsa.PublicKey:
+			if public.N.BitLen() < minRSAKeyBits {
+				return fmt.Errorf("key %s: RSA keys need at least %d bits", kid, minRSAKeyBits)
+			}
+			k.keys[kid] = verificationKey{method: jwt.SigningMethodRS256, public: public}
+		case ed25519.PublicKey:
+			k.keys[kid] = verificationKey{method: jwt.SigningMethodEdDSA, public: public}
+		default:
+			return fmt.Errorf("key %s: only RSA and Ed25519 keys are supported", kid)
+		}
+	}
+
+	if signingKid == "" {
+		if len(privateKeys) != 1 {
+			return fmt.Errorf("%s holds %d private keys, pick the signing one with JWT_SIGNING_KEY_ID", dir, len(privateKeys))
+		}
+
+		for kid := range privateKeys {
+			signingKid = kid
+		}
+	}
+
+	signingKey, ok := privateKeys[signingKid]
+
+	if !ok {
+		return fmt.Errorf("no private key %s in %s", signingKid, dir)
+	}
+
+	k.signingKid, k.signingKey, k.method = signingKid, signingKey, k.keys[signingKid].method
+	log.Printf("Signing tokens with key %s, %d keys verify\n", signingKid, len(k.keys))
+	return nil
+}
+
+// readPEMKey reads the first key of a PEM file
+func readPEMKey(path string) (any, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// Sign signs the claims with the signing key, naming it in the kid header
+func (k *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+
+	if k.signingKid != "" {
+		token.Header["kid"] = k.signingKid
+	}
+
+	return token.SignedString(k.signingKey)
+}
+
+// Parse verifies a token with the key its kid header names and reads its claims
+func (k *KeyManager) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+	if k.legacySecret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	return jwt.ParseWithClaims(tokenString, claims, k.keyFor, jwt.WithValidMethods(methods))
+}
+
+// keyFor picks the verification key of a token. The key decides the
+// algorithm, a token can't pick a weaker one by naming it in its header.
+func (k *KeyManager) keyFor(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if kid == "" {
+		if k.legacySecret != nil && token.Method == jwt.SigningMethodHS256 {
+			return k.legacySecret, nil
+		}
+
+		return nil, errUnknownKey
+	}
+
+	key, ok := k.keys[kid]
+
+	if !ok || token.Method.Alg() != key.method.Alg() {
+		return nil, errUnknownKey
+	}
+
+	return key.public, nil
+}
+
+// JWKS returns every verification key as a JSON Web Key Set. The legacy
+// secret is symmetric and never published.
+func (k *KeyManager) JWKS() JWKSetDTO {
+	set := JWKSetDTO{Keys: make([]JWKDTO, 0, len(k.keys))}
+
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := JWKDTO{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
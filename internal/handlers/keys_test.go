@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKeys are generated once, RSA keys take a while
+var testKeys = struct {
+	rsa     *rsa.PrivateKey
+	ed      ed25519.PrivateKey
+	foreign ed25519.PrivateKey
+}{}
+
+func init() {
+	var err error
+
+	if testKeys.rsa, err = rsa.GenerateKey(rand.Reader, minRSAKeyBits); err != nil {
+		panic(err)
+	}
+
+	if _, testKeys.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		panic(err)
+	}
+
+	if _, testKeys.foreign, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		panic(err)
+	}
+}
+
+// writeKeys writes a keys dir with a PEM file per kid, private keys in
+// PKCS#8 and the public half only of the keys in publicOnly
+func writeKeys(t *testing.T, private map[string]any, publicOnly map[string]any) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for kid, key := range private {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writePEM(t, filepath.Join(dir, kid+".pem"), "PRIVATE KEY", der)
+	}
+
+	for kid, key := range publicOnly {
+		der, err := x509.MarshalPKIXPublicKey(key)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writePEM(t, filepath.Join(dir, kid+".pem"), "PUBLIC KEY", der)
+	}
+
+	return dir
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+}
+
+// signWith signs test claims with any key and header, bypassing the key manager
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestKeyManagerSigningKey(t *testing.T) {
+	both := map[string]any{"old": testKeys.rsa, "new": testKeys.ed}
+
+	tests := []struct {
+		name       string
+		private    map[string]any
+		publicOnly map[string]any
+		signingKid string
+		// wantKid and wantAlg are the header of signed tokens, wantErr a part
+		// of the error when loading fails
+		wantKid string
+		wantAlg string
+		wantErr string
+	}{
+		{"PickedByKid", both, nil, "new", "new", "EdDSA", ""},
+		{"PickedRSA", both, nil, "old", "old", "RS256", ""},
+		{"OnlyPrivateKey", map[string]any{"new": testKeys.ed}, map[string]any{"old": &testKeys.rsa.PublicKey}, "", "new", "EdDSA", ""},
+		{"AmbiguousWithoutKid", both, nil, "", "", "", "pick the signing one"},
+		{"UnknownKid", both, nil, "missing", "", "", "no private key missing"},
+		{"PublicKeyCantSign", map[string]any{"new": testKeys.ed}, map[string]any{"old": &testKeys.rsa.PublicKey}, "old", "", "", "no private key old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ProvideKeyManager(writeKeys(t, tt.private, tt.publicOnly), tt.signingKid, "")
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error about %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := testClaims()
+			signed, err := keys.Sign(claims)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := keys.Parse(signed, &jwt.RegisteredClaims{})
+
+			if err != nil || !token.Valid {
+				t.Fatalf("a token the manager signed doesn't verify: %v", err)
+			}
+
+			if token.Header["kid"] != tt.wantKid || token.Method.Alg() != tt.wantAlg {
+				t.Fatalf("expected kid %s with %s, got %v with %s", tt.wantKid, tt.wantAlg, token.Header["kid"], token.Method.Alg())
+			}
+		})
+	}
+}
+
+func TestKeyManagerRejectsWeakRSAKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ProvideKeyManager(writeKeys(t, map[string]any{"weak": weak}, nil), "", "")
+
+	if err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Fatalf("expected a 1024 bit RSA key to be rejected, got %v", err)
+	}
+}
+
+func TestKeyManagerParse(t *testing.T) {
+	// "new" signs, "old" was rotated out and only verifies
+	keys, err := ProvideKeyManager(writeKeys(t, map[string]any{"new": testKeys.ed}, map[string]any{"old": &testKeys.rsa.PublicKey}), "", "legacy")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noLegacy, err := ProvideKeyManager(writeKeys(t, map[string]any{"new": testKeys.ed}, nil), "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Public := []byte(testKeys.ed.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name  string
+		keys  *KeyManager
+		token string
+		valid bool
+	}{
+		{"SigningKey", keys, signWith(t, jwt.SigningMethodEdDSA, "new", testKeys.ed), true},
+		{"RotatedOutKey", keys, signWith(t, jwt.SigningMethodRS256, "old", testKeys.rsa), true},
+		{"LegacySecretWithoutKid", keys, signWith(t, jwt.SigningMethodHS256, "", []byte("legacy")), true},
+		{"UnknownKid", keys, signWith(t, jwt.SigningMethodEdDSA, "ghost", testKeys.ed), false},
+		{"NoKidWithoutLegacySecret", noLegacy, signWith(t, jwt.SigningMethodHS256, "", []byte("legacy")), false},
+		{"AsymmetricWithoutKid", keys, signWith(t, jwt.SigningMethodEdDSA, "", testKeys.ed), false},
+		{"ForeignKeyUnderKnownKid", keys, signWith(t, jwt.SigningMethodEdDSA, "new", testKeys.foreign), false},
+		{"WrongLegacySecret", keys, signWith(t, jwt.SigningMethodHS256, "", []byte("guess")), false},
+		// the key decides the algorithm, a token can't switch to another
+		{"RS256UnderEd25519Kid", keys, signWith(t, jwt.SigningMethodRS256, "new", testKeys.rsa), false},
+		{"EdDSAUnderRSAKid", keys, signWith(t, jwt.SigningMethodEdDSA, "old", testKeys.ed), false},
+		{"HS256WithPublicKeyAsSecret", keys, signWith(t, jwt.SigningMethodHS256, "new", ed25519Public), false},
+		{"HS256WithLegacySecretUnderKid", keys, signWith(t, jwt.SigningMethodHS256, "new", []byte("legacy")), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.keys.Parse(tt.token, &jwt.RegisteredClaims{})
+			valid := err == nil && token.Valid
+
+			if valid != tt.valid {
+				t.Fatalf("expected valid=%v, got %v (%v)", tt.valid, valid, err)
+			}
+		})
+	}
+}
+
+func TestKeyManagerJWKS(t *testing.T) {
+	keys, err := ProvideKeyManager(writeKeys(t, map[string]any{"new": testKeys.ed}, map[string]any{"old": &testKeys.rsa.PublicKey}), "", "legacy")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := keys.JWKS()
+
+	// every verification key is published sorted by kid, the legacy secret never
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "new" || set.Keys[0].Kty != "OKP" || set.Keys[1].Kid != "old" || set.Keys[1].Kty != "RSA" {
+		t.Fatalf("unexpected key set %+v", set)
+	}
+}
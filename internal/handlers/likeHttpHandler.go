@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+func (u *UserHttpHandler) LikeTweet(w http.ResponseWriter, r *http.Request) {
+	u.changeLike(w, r, u.uuc.LikeTweet)
+}
+
+func (u *UserHttpHandler) UnlikeTweet(w http.ResponseWriter, r *http.Request) {
+	u.changeLike(w, r, u.uuc.UnlikeTweet)
+}
+
+// GetTweetLikers lists the users who liked the tweet in the path
+func (u *UserHttpHandler) GetTweetLikers(w http.ResponseWriter, r *http.Request) {
+	tweetId, err := strconv.Atoi(chi.URLParam(r, "tweetId"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	userPage, err := u.uuc.ListTweetLikers(r.Context(), tweetId, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	stats, err := u.uuc.GetLikeStats(r.Context(), 0, []int{tweetId})
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := UserPageResponseDTO{
+		Users:      make([]UserSummaryDTO, 0, len(userPage.Users)),
+		Count:      stats[tweetId].Count,
+		NextCursor: userPage.NextCursor,
+	}
+
+	for _, user := range userPage.Users {
+		response.Users = append(response.Users, userSummary(user))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetUserLikes lists the tweets the user in the path liked, most recent tweets first
+func (u *UserHttpHandler) GetUserLikes(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	tweetPage, err := u.uuc.ListUserLikes(r.Context(), userId, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithTweetPage(w, r, viewerId(r), tweetPage)
+}
+
+// changeLike runs a like or unlike of the tweet in the path on behalf of the
+// authenticated user and responds with the tweet's new like count
+func (u *UserHttpHandler) changeLike(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, userId int, tweetId int) error,
+) {
+	userId := requestPrincipal(r).UserId
+
+	tweetId, err := strconv.Atoi(chi.URLParam(r, "tweetId"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	if err := change(r.Context(), userId, tweetId); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	stats, err := u.uuc.GetLikeStats(r.Context(), userId, []int{tweetId})
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, LikeStatsResponseDTO{
+		TweetID:   tweetId,
+		LikeCount: stats[tweetId].Count,
+		Liked:     stats[tweetId].Liked,
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import "net/http"
+
+// GetNotifications lists the notifications of the authenticated user, newest first
+func (u *UserHttpHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserId
+
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notificationPage, err := u.uuc.ListNotifications(r.Context(), userId, unreadOnly, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	unread, err := u.uuc.CountUnreadNotifications(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := NotificationPageResponseDTO{
+		Notifications: make([]NotificationDTO, 0, len(notificationPage.Notifications)),
+		UnreadCount:   unread,
+		NextCursor:    notificationPage.NextCursor,
+	}
+
+	for _, notification := range notificationPage.Notifications {
+		response.Notifications = append(response.Notifications, NotificationDTO{
+			ID:        notification.Id,
+			Kind:      notification.Kind,
+			ActorID:   notification.ActorId,
+			TweetID:   notification.TweetId,
+			Read:      notification.Read,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// MarkNotificationsRead marks notifications of the authenticated user read
+func (u *UserHttpHandler) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserId
+
+	request := MarkNotificationsReadRequestDTO{}
+
+	// an empty body marks everything read
+	if err := decodeOptionalBody(r, &request); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	if err := u.uuc.MarkNotificationsRead(r.Context(), userId, request.IDs); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	unread, err := u.uuc.CountUnreadNotifications(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, UnreadNotificationsResponseDTO{UnreadCount: unread})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ForgotPassword emails a password reset token to the address in the body.
+// It is accepted whether or not the address has an account.
+func (u *UserHttpHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	request := ForgotPasswordRequestDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
+	}
+
+	if err := u.uuc.RequestPasswordReset(r.Context(), request.Email); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, "")
+}
+
+// ResetPassword sets a new password with an emailed reset token, which logs
+// the user out of every session
+func (u *UserHttpHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	request := ResetPasswordRequestDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
+	}
+
+	if err := u.uuc.ResetPassword(r.Context(), request.Token, request.Password); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, "")
+}
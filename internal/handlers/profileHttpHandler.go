@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+// GetProfile responds with the public profile of the user in the path, who is
+// named by handle or, as handles are never all digits, by id
+func (u *UserHttpHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	handle := chi.URLParam(r, "handle")
+
+	var user domain.User
+	var err error
+
+	if userId, errId := strconv.Atoi(handle); errId == nil {
+		user, err = u.uuc.GetUserById(r.Context(), userId)
+	} else {
+		user, err = u.uuc.GetUserByHandle(r.Context(), handle)
+	}
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithProfile(w, r, user)
+}
+
+// UpdateProfile replaces the authenticated user's profile, fields left out
+// of the body are cleared
+func (u *UserHttpHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserId
+
+	profileRequest := ProfileDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&profileRequest); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
+	}
+
+	if err := u.uuc.UpdateProfile(r.Context(), userId, fromProfileDTO(profileRequest)); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	user, err := u.uuc.GetUserById(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithProfile(w, r, user)
+}
+
+func (u *UserHttpHandler) respondWithProfile(w http.ResponseWriter, r *http.Request, user domain.User) {
+	stats, err := u.uuc.GetFollowStats(r.Context(), user.ID)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ProfileResponseDTO{
+		UserSummaryDTO: userSummary(user),
+		FollowersCount: stats.Followers,
+		FollowingCount: stats.Following,
+	})
+}
+
+func userSummary(user domain.User) UserSummaryDTO {
+	return UserSummaryDTO{
+		ID:          user.ID,
+		IsChirpyRed: user.IsChirpyRed,
+		ProfileDTO:  toProfileDTO(user.Profile),
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+func toProfileDTO(profile domain.Profile) ProfileDTO {
+	return ProfileDTO{
+		Handle:      profile.Handle,
+		DisplayName: profile.DisplayName,
+		Bio:         profile.Bio,
+		Location:    profile.Location,
+	}
+}
+
+func fromProfileDTO(profile ProfileDTO) domain.Profile {
+	return domain.Profile{
+		Handle:      profile.Handle,
+		DisplayName: profile.DisplayName,
+		Bio:         profile.Bio,
+		Location:    profile.Location,
+	}
+}
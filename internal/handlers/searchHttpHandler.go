@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+// SearchTweets runs the search in the q query parameter, ranked by relevance
+// unless sort asks for the most recent tweets first
+func (u *UserHttpHandler) SearchTweets(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	order := domain.SearchOrder(r.URL.Query().Get("sort"))
+
+	tweetPage, err := u.uuc.SearchTweets(r.Context(), r.URL.Query().Get("q"), order, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithTweetPage(w, r, viewerId(r), tweetPage)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+// GetSessions lists where the authenticated user is logged in
+func (u *UserHttpHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	caller := requestPrincipal(r)
+
+	u.respondWithSessions(w, r, caller.UserId, caller.SessionId)
+}
+
+// DeleteSession logs the session in the path out
+func (u *UserHttpHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	caller := requestPrincipal(r)
+
+	if err := u.uuc.RevokeSession(r.Context(), caller.UserId, chi.URLParam(r, "id")); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithSessions(w, r, caller.UserId, caller.SessionId)
+}
+
+// RevokeAllSessions logs the authenticated user out everywhere, the current
+// session included. The access tokens of the sessions stop working with them.
+func (u *UserHttpHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	caller := requestPrincipal(r)
+
+	if err := u.uuc.RevokeSessions(r.Context(), caller.UserId, ""); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithSessions(w, r, caller.UserId, caller.SessionId)
+}
+
+func (u *UserHttpHandler) respondWithSessions(w http.ResponseWriter, r *http.Request, userId int, currentSessionId string) {
+	sessions, err := u.uuc.ListSessions(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := SessionsResponseDTO{Sessions: make([]SessionDTO, 0, len(sessions))}
+
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, SessionDTO{
+			ID:         session.Id,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			Current:    session.Id == currentSessionId,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// requestClient tells who sent the request. The IP is the peer address,
+// proxy headers are not trusted.
+func requestClient(r *http.Request) domain.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return domain.Client{UserAgent: r.UserAgent(), IP: ip}
+}
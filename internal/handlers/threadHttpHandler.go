@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+// GetThread shows the tweet in the path within its conversation
+func (u *UserHttpHandler) GetThread(w http.ResponseWriter, r *http.Request) {
+	tweetId, err := strconv.Atoi(chi.URLParam(r, "tweetId"))
+
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	thread, err := u.uuc.GetThread(r.Context(), tweetId, page)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	// annotate every tweet of the thread in one go
+	tweets := append([]domain.Tweet(nil), thread.Ancestors...)
+	var collect func(node domain.ThreadNode)
+	collect = func(node domain.ThreadNode) {
+		tweets = append(tweets, node.Tweet)
+		for _, reply := range node.Replies {
+			collect(reply)
+		}
+	}
+	collect(thread.ThreadNode)
+
+	tweetResponses, err := u.tweetResponses(r.Context(), viewerId(r), tweets)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	byId := make(map[int]TweetResponseDTO, len(tweetResponses))
+
+	for _, response := range tweetResponses {
+		byId[response.TweetId] = response
+	}
+
+	var toResponse func(node domain.ThreadNode) ThreadNodeResponseDTO
+	toResponse = func(node domain.ThreadNode) ThreadNodeResponseDTO {
+		response := ThreadNodeResponseDTO{
+			TweetResponseDTO: byId[node.Tweet.TweetId],
+			ReplyCount:       node.ReplyCount,
+			Replies:          make([]ThreadNodeResponseDTO, 0, len(node.Replies)),
+			NextCursor:       node.NextCursor,
+		}
+		for _, reply := range node.Replies {
+			response.Replies = append(response.Replies, toResponse(reply))
+		}
+		return response
+	}
+
+	respondWithJSON(w, http.StatusOK, ThreadResponseDTO{
+		Ancestors:             tweetResponses[:len(thread.Ancestors)],
+		ThreadNodeResponseDTO: toResponse(thread.ThreadNode),
+	})
+}
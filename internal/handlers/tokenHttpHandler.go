@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/go-chi/chi"
+)
+
+// CreateAccessToken mints a personal access token for the authenticated user
+func (u *UserHttpHandler) CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	tokenRequest := AccessTokenRequestDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&tokenRequest); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
+	}
+
+	scopes := make([]domain.Scope, 0, len(tokenRequest.Scopes))
+	for _, scope := range tokenRequest.Scopes {
+		scopes = append(scopes, domain.Scope(scope))
+	}
+
+	token, secret, err := u.uuc.CreateAccessToken(r.Context(), requestPrincipal(r).UserId, tokenRequest.Name, scopes)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, AccessTokenCreatedDTO{
+		AccessTokenDTO: toAccessTokenDTO(token),
+		Token:          secret,
+	})
+}
+
+// GetAccessTokens lists the authenticated user's personal access tokens
+func (u *UserHttpHandler) GetAccessTokens(w http.ResponseWriter, r *http.Request) {
+	u.respondWithAccessTokens(w, r, requestPrincipal(r).UserId)
+}
+
+// DeleteAccessToken revokes the personal access token in the path
+func (u *UserHttpHandler) DeleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserId
+
+	if err := u.uuc.RevokeAccessToken(r.Context(), userId, chi.URLParam(r, "id")); err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	u.respondWithAccessTokens(w, r, userId)
+}
+
+func (u *UserHttpHandler) respondWithAccessTokens(w http.ResponseWriter, r *http.Request, userId int) {
+	tokens, err := u.uuc.ListAccessTokens(r.Context(), userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	response := AccessTokensResponseDTO{Tokens: make([]AccessTokenDTO, 0, len(tokens))}
+
+	for _, token := range tokens {
+		response.Tokens = append(response.Tokens, toAccessTokenDTO(token))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func toAccessTokenDTO(token domain.AccessToken) AccessTokenDTO {
+	scopes := make([]string, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	return AccessTokenDTO{
+		ID:        token.Id,
+		Name:      token.Name,
+		Scopes:    scopes,
+		CreatedAt: token.CreatedAt,
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
-	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/anandh86/chirpy/internal/core/domain"
@@ -16,23 +19,22 @@ import (
 	"github.com/joho/godotenv"
 )
 
-func ProvideUserHttpHandler(uuc ports.IUseCase) *UserHttpHandler {
+func ProvideUserHttpHandler(uuc ports.IUseCase, keys *KeyManager) *UserHttpHandler {
 	// by default, godotenv will look for a file named .env in the current directory
 	godotenv.Load()
 
-	jwtSecret := os.Getenv("JWT_SECRET")
 	apiKey := os.Getenv("POLKA_KEY")
 
 	return &UserHttpHandler{
 		uuc:         uuc,
-		token:       jwtSecret,
+		keys:        keys,
 		polkaApiKey: apiKey,
 	}
 }
 
 type UserHttpHandler struct {
 	uuc         ports.IUseCase
-	token       string
+	keys        *KeyManager
 	polkaApiKey string
 }
 
@@ -45,44 +47,68 @@ func (u *UserHttpHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	err := decoder.Decode(&userRequest)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters")
+		respondWithDomainError(w, errMalformedBody)
 		return
 	}
 
-	userResponse, errCreation := u.uuc.CreateUser(userRequest.Email, userRequest.Password)
+	userResponse, errCreation := u.uuc.CreateUser(r.Context(), userRequest.Email, userRequest.Password, fromProfileDTO(userRequest.ProfileDTO))
 
 	if errCreation != nil {
-		respondWithError(w, http.StatusBadRequest, "account present already")
+		respondWithDomainError(w, errCreation)
 		return
 	}
 	userResponseDTO := UserResponseDTO{
-		ID:    userResponse.ID,
-		Email: userResponse.Email,
+		ID:          userResponse.ID,
+		Email:       userResponse.Email,
+		IsChirpyRed: userResponse.IsChirpyRed,
+		ProfileDTO:  toProfileDTO(userResponse.Profile),
+		Role:        string(userResponse.Role),
+		CreatedAt:   userResponse.CreatedAt,
+		UpdatedAt:   userResponse.UpdatedAt,
 	}
 	respondWithJSON(w, http.StatusCreated, userResponseDTO)
 }
 
-func (u *UserHttpHandler) createJWTToken(userId int, expiresInSeconds int, issuer string) (string, error) {
-	mySigningKey := []byte(u.token)
-	expiresAt := time.Now().Add(time.Duration(expiresInSeconds) * time.Second)
+// chirpyClaims are the claims of the tokens chirpy issues, access tokens
+// carry the id of the session they were issued for
+type chirpyClaims struct {
+	jwt.RegisteredClaims
+	SessionId string `json:"sid,omitempty"`
+}
 
-	// create the claims
-	claims := &jwt.RegisteredClaims{
-		Issuer:    issuer,
-		Subject:   strconv.Itoa(userId),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(expiresAt),
+func (u *UserHttpHandler) createJWTToken(userId int, sessionId string, expiresAt time.Time, issuer string) (string, error) {
+	// a random id keeps two tokens issued within the same second apart
+	tokenId := make([]byte, 16)
+
+	if _, err := rand.Read(tokenId); err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(mySigningKey)
+	// create the claims
+	claims := &chirpyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(tokenId),
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		SessionId: sessionId,
+	}
+
+	return u.keys.Sign(claims)
 }
 
 func (u *UserHttpHandler) Revoke(w http.ResponseWriter, r *http.Request) {
-	tokenString := fetchBearerToken(r)
+	tokenString, err := authorizationCredentials(r, "Bearer")
 
-	if !u.uuc.RevokeRefreshToken(tokenString) {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	if err := u.uuc.RevokeRefreshToken(r.Context(), tokenString); err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
@@ -91,54 +117,63 @@ func (u *UserHttpHandler) Revoke(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u *UserHttpHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	tokenString := fetchBearerToken(r)
+	tokenString, err := authorizationCredentials(r, "Bearer")
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
 
-	isValidToken, jwtToken := isValidToken(tokenString, u.token)
+	holder, err := u.parseToken(tokenString, refreshTokenIssuer)
 
-	if !isValidToken {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
-	tokenIssuer, _ := jwtToken.Claims.GetIssuer()
+	userId := holder.UserId
+
+	// every refresh swaps the refresh token for a new one
+	refreshToken, expiresAt, err := u.generateRefreshToken(userId)
 
-	if tokenIssuer != "chirpy-refresh" {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
-	if u.uuc.IsRefreshTokenRevoked(tokenString) {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	sessionId, err := u.uuc.RotateRefreshToken(r.Context(), tokenString, refreshToken, expiresAt, requestClient(r))
+
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
 	// generate new access token
-	userIdStr, _ := jwtToken.Claims.GetSubject()
-	userId, _ := strconv.Atoi(userIdStr)
-
-	accessToken, _ := u.generateAccessToken(userId)
+	accessToken, _ := u.generateAccessToken(userId, sessionId)
 
 	response := map[string]string{
-		"token": accessToken,
+		"token":         accessToken,
+		"refresh_token": refreshToken,
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func (u *UserHttpHandler) generateAccessToken(userId int) (string, error) {
-	issuer := "chirpy-access"
+func (u *UserHttpHandler) generateAccessToken(userId int, sessionId string) (string, error) {
+	issuer := accessTokenIssuer
 	// access tokens should expire in one hour
-	expiryInSeconds := int(time.Hour.Seconds())
+	expiresAt := time.Now().Add(time.Hour)
 
-	return u.createJWTToken(userId, expiryInSeconds, issuer)
+	return u.createJWTToken(userId, sessionId, expiresAt, issuer)
 }
 
-func (u *UserHttpHandler) generateRefreshToken(userId int) (string, error) {
-	issuer := "chirpy-refresh"
+func (u *UserHttpHandler) generateRefreshToken(userId int) (string, time.Time, error) {
+	issuer := refreshTokenIssuer
 	// refresh tokens should expire in 60 days
-	expireIn60Days := 60 * 24 * time.Hour
+	expiresAt := time.Now().Add(60 * 24 * time.Hour)
 
-	return u.createJWTToken(userId, int(expireIn60Days.Seconds()), issuer)
+	token, err := u.createJWTToken(userId, "", expiresAt, issuer)
+	return token, expiresAt, err
 }
 
 func (u *UserHttpHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
@@ -149,30 +184,41 @@ func (u *UserHttpHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	err := decoder.Decode(&userRequest)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Malformed json body")
+		respondWithDomainError(w, errMalformedBody)
 		return
 	}
 
 	// call use case
-	userId, err1 := u.uuc.LoginUser(userRequest.Email, userRequest.Password)
+	userId, err1 := u.uuc.LoginUser(r.Context(), userRequest.Email, userRequest.Password)
 
 	if err1 != nil {
 		// Login failed
-		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
+		respondWithDomainError(w, err1)
 		return
 	}
 
-	// create access token
-	accessToken, _ := u.generateAccessToken(userId)
+	// create refresh token, it starts a new session
+	refreshToken, expiresAt, err := u.generateRefreshToken(userId)
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
+
+	sessionId, err := u.uuc.IssueRefreshToken(r.Context(), userId, refreshToken, expiresAt, requestClient(r))
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
 
-	// create refresh token
-	refreshToken, _ := u.generateRefreshToken(userId)
-	u.uuc.StoreRefreshToken(refreshToken)
+	// create access token
+	accessToken, _ := u.generateAccessToken(userId, sessionId)
 
-	repoUser, errFunc := u.uuc.GetUserById(userId)
+	repoUser, errFunc := u.uuc.GetUserById(r.Context(), userId)
 
 	if errFunc != nil {
-		respondWithError(w, http.StatusBadRequest, "Server issues")
+		respondWithDomainError(w, errFunc)
 		return
 	}
 
@@ -183,232 +229,341 @@ func (u *UserHttpHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		IsChirpyRed:  repoUser.IsChirpyRed,
+		ProfileDTO:   toProfileDTO(repoUser.Profile),
+		Role:         string(repoUser.Role),
+		CreatedAt:    repoUser.CreatedAt,
+		UpdatedAt:    repoUser.UpdatedAt,
 	}
 	respondWithJSON(w, http.StatusOK, userResponseDTO)
 }
 
-func fetchApiKey(r *http.Request) string {
-	authorizationHeaderToken := r.Header.Get("Authorization")
+// GetJWKS publishes the keys that verify chirpy's tokens
+func (u *UserHttpHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, u.keys.JWKS())
+}
+
+// UpdateUser applies a partial update to the authenticated user's account,
+// fields left out of the body keep their value
+func (u *UserHttpHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
-	if authorizationHeaderToken == "" {
-		return ""
+	caller := requestPrincipal(r)
+
+	userRequest := UserUpdateRequestDTO{}
+
+	if err := json.NewDecoder(r.Body).Decode(&userRequest); err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
 	}
 
-	parts := strings.Fields(authorizationHeaderToken)
+	repoUser, err := u.uuc.UpdateUser(r.Context(), caller.UserId, caller.SessionId, domain.UserUpdate{
+		Email:           userRequest.Email,
+		Password:        userRequest.Password,
+		CurrentPassword: userRequest.CurrentPassword,
+	})
 
-	if parts[0] != "ApiKey" {
-		return ""
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
 	}
 
-	return parts[1]
+	// presentation segment
+	userResponseDTO := UserResponseDTO{
+		ID:          repoUser.ID,
+		Email:       repoUser.Email,
+		IsChirpyRed: repoUser.IsChirpyRed,
+		ProfileDTO:  toProfileDTO(repoUser.Profile),
+		Role:        string(repoUser.Role),
+		CreatedAt:   repoUser.CreatedAt,
+		UpdatedAt:   repoUser.UpdatedAt,
+	}
+	respondWithJSON(w, http.StatusOK, userResponseDTO)
 }
 
-func fetchBearerToken(r *http.Request) string {
-	bearerToken := r.Header.Get("Authorization")
+func (u *UserHttpHandler) PostTweet(w http.ResponseWriter, r *http.Request) {
+	authorId := requestPrincipal(r).UserId
+
+	decoder := json.NewDecoder(r.Body)
+	tweetRequest := TweetRequestDTO{}
+
+	err := decoder.Decode(&tweetRequest)
 
-	if bearerToken == "" {
-		return bearerToken
+	if err != nil {
+		respondWithDomainError(w, errMalformedBody)
+		return
 	}
 
-	parts := strings.Fields(bearerToken)
+	draft := domain.TweetDraft{
+		Body:              tweetRequest.Body,
+		Kind:              tweetRequest.Kind,
+		ReferencedTweetId: tweetRequest.ReferencedTweetId,
+		InReplyTo:         tweetRequest.InReplyTo,
+	}
 
-	return parts[1]
-}
+	tweet, errPost := u.uuc.PostTweet(r.Context(), draft, authorId)
 
-func isValidToken(jwtToken string, tokenSecret string) (bool, *jwt.Token) {
+	if errPost != nil {
+		respondWithDomainError(w, errPost)
+		return
+	}
 
-	claimsStruct := jwt.RegisteredClaims{}
+	tweetResponses, err := u.tweetResponses(r.Context(), authorId, []domain.Tweet{tweet})
 
-	token, err := jwt.ParseWithClaims(
-		jwtToken,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
-	)
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
 
-	return (err == nil && token.Valid), token
+	respondWithJSON(w, http.StatusCreated, tweetResponses[0])
 }
 
-func (u *UserHttpHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
-
-	tokenString := fetchBearerToken(r)
+func (u *UserHttpHandler) DeleteTweet(w http.ResponseWriter, r *http.Request) {
+	// Read the input parameter
+	tweetIdStr := chi.URLParam(r, "tweetId")
+	tweetId, err := strconv.Atoi(tweetIdStr)
 
-	isValidToken, jwtToken := isValidToken(tokenString, u.token)
+	authorId := requestPrincipal(r).UserId
 
-	if !isValidToken {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil || tweetId == 0 {
+		respondWithDomainError(w, errInvalidParameters)
 		return
 	}
 
-	tokenIssuer, _ := jwtToken.Claims.GetIssuer()
-
-	if tokenIssuer != "chirpy-access" {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if deletionErr := u.uuc.DeleteTweet(r.Context(), tweetId, authorId); deletionErr != nil {
+		respondWithDomainError(w, deletionErr)
 		return
 	}
 
-	// update the user info
-	userId, _ := jwtToken.Claims.GetSubject()
-	userIdInt, _ := strconv.Atoi(userId)
+	respondWithJSON(w, http.StatusOK, "all good")
+}
 
-	decoder := json.NewDecoder(r.Body)
-	userRequest := UserRequestDTO{}
-	errDecode := decoder.Decode(&userRequest)
+func (u *UserHttpHandler) GetTweetById(w http.ResponseWriter, r *http.Request) {
+
+	// Read the input parameter
+	tweetIdStr := chi.URLParam(r, "tweetId")
+	tweetId, err := strconv.Atoi(tweetIdStr)
 
-	if errDecode != nil {
-		respondWithError(w, http.StatusBadRequest, "Malformed json body")
+	if err != nil {
+		respondWithDomainError(w, errInvalidParameters)
 		return
 	}
 
-	if authErr := u.uuc.UpdateUser(userIdInt, userRequest.Email, userRequest.Password); authErr != nil {
-		respondWithError(w, http.StatusUnauthorized, "got issues")
+	tweet, err1 := u.uuc.GetTweetById(r.Context(), tweetId)
+
+	if err1 != nil {
+		respondWithDomainError(w, err1)
 		return
 	}
 
-	// presentation segment
-	userResponseDTO := UserResponseDTO{
-		ID:    userIdInt,
-		Email: userRequest.Email,
+	tweetResponses, err := u.tweetResponses(r.Context(), viewerId(r), []domain.Tweet{tweet})
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
 	}
-	respondWithJSON(w, http.StatusOK, userResponseDTO)
-}
 
-func (u *UserHttpHandler) PostTweet(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, tweetResponses[0])
+}
 
-	// authenticate the user first
-	tokenString := fetchBearerToken(r)
+func (u *UserHttpHandler) GetAllTweets(w http.ResponseWriter, r *http.Request) {
 
-	isValidToken, jwtToken := isValidToken(tokenString, u.token)
+	filter := domain.TweetFilter{
+		Descending: r.URL.Query().Get("sort") == "desc",
+	}
 
-	if !isValidToken {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
-		return
+	// s is a string that contains the value of the author_id query parameter
+	// if it exists, or an empty string if it doesn't
+	if authorIdStr := r.URL.Query().Get("author_id"); authorIdStr != "" {
+		author_id, errParse := strconv.Atoi(authorIdStr)
+		if errParse != nil {
+			respondWithDomainError(w, errInvalidParameters)
+			return
+		}
+		filter.AuthorId = author_id
 	}
 
-	tokenIssuer, _ := jwtToken.Claims.GetIssuer()
+	since, errSince := parseTimeParam(r, "since")
+	until, errUntil := parseTimeParam(r, "until")
 
-	if tokenIssuer != "chirpy-access" {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if errSince != nil || errUntil != nil {
+		respondWithDomainError(w, errInvalidParameters)
 		return
 	}
 
-	// figure out author's id
-	authorIdStr, _ := jwtToken.Claims.GetSubject()
-	authorId, _ := strconv.Atoi(authorIdStr)
+	filter.Since = since
+	filter.Until = until
 
-	decoder := json.NewDecoder(r.Body)
-	tweetRequest := domain.Tweet{}
+	page, errPage := parsePageRequest(r)
 
-	err := decoder.Decode(&tweetRequest)
+	if errPage != nil {
+		respondWithDomainError(w, errPage)
+		return
+	}
+
+	tweetPage, err := u.uuc.ListTweets(r.Context(), filter, page)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters")
+		respondWithDomainError(w, err)
 		return
 	}
 
-	tweetResponse, _ := u.uuc.PostTweet(tweetRequest.Body, authorId)
-	tweetResponse.AuthorId = authorId
-	respondWithJSON(w, http.StatusCreated, tweetResponse)
+	u.respondWithTweetPage(w, r, viewerId(r), tweetPage)
 }
 
-func (u *UserHttpHandler) DeleteTweet(w http.ResponseWriter, r *http.Request) {
-	// Read the input parameter
-	tweetIdStr := chi.URLParam(r, "tweetId")
-	tweetId, err := strconv.Atoi(tweetIdStr)
+func (u *UserHttpHandler) HomeTimeline(w http.ResponseWriter, r *http.Request) {
 
-	// authenticate the user first
-	tokenString := fetchBearerToken(r)
+	userId := requestPrincipal(r).UserId
 
-	isValidToken, jwtToken := isValidToken(tokenString, u.token)
+	page, err := parsePageRequest(r)
 
-	if !isValidToken {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
-	tokenIssuer, _ := jwtToken.Claims.GetIssuer()
+	tweetPage, err := u.uuc.HomeTimeline(r.Context(), userId, page)
 
-	if tokenIssuer != "chirpy-access" {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
-	// figure out author's id
-	authorIdStr, _ := jwtToken.Claims.GetSubject()
-	authorId, _ := strconv.Atoi(authorIdStr)
+	u.respondWithTweetPage(w, r, userId, tweetPage)
+}
 
-	if err != nil || tweetId == 0 {
-		respondWithError(w, http.StatusInternalServerError, "Invalid parameters")
-		return
-	}
+// respondWithTweetPage writes a page of tweets annotated with their likes
+func (u *UserHttpHandler) respondWithTweetPage(w http.ResponseWriter, r *http.Request, viewerId int, tweetPage domain.TweetPage) {
+	tweets, err := u.tweetResponses(r.Context(), viewerId, tweetPage.Tweets)
 
-	if deletionErr := u.uuc.DeleteTweet(tweetId, authorId); deletionErr != nil {
-		respondWithError(w, http.StatusForbidden, "Unauthorized")
+	if err != nil {
+		respondWithDomainError(w, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, "all good")
+	respondWithJSON(w, http.StatusOK, TweetPageResponseDTO{
+		Tweets:     tweets,
+		NextCursor: tweetPage.NextCursor,
+	})
 }
 
-func (u *UserHttpHandler) GetTweetById(w http.ResponseWriter, r *http.Request) {
+// tweetResponses annotates tweets with their engagement as seen by the viewer
+// and embeds the tweets that retweets and quotes reference
+func (u *UserHttpHandler) tweetResponses(ctx context.Context, viewerId int, tweets []domain.Tweet) ([]TweetResponseDTO, error) {
+	tweetIds := make([]int, 0, len(tweets))
+	referenced := make(map[int]domain.Tweet)
 
-	// Read the input parameter
-	tweetIdStr := chi.URLParam(r, "tweetId")
-	tweetId, err := strconv.Atoi(tweetIdStr)
+	for _, tweet := range tweets {
+		tweetIds = append(tweetIds, tweet.TweetId)
+	}
+
+	for _, tweet := range tweets {
+		referencedId := tweet.ReferencedTweetId
+
+		if _, seen := referenced[referencedId]; referencedId == 0 || seen {
+			continue
+		}
+
+		referencedTweet, err := u.uuc.GetTweetById(ctx, referencedId)
+
+		if errors.Is(err, domain.ErrNotFound) {
+			// the original is gone, the repost stays without it
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		referenced[referencedId] = referencedTweet
+		tweetIds = append(tweetIds, referencedId)
+	}
+
+	likes, err := u.uuc.GetLikeStats(ctx, viewerId, tweetIds)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Invalid parameters")
-		return
+		return nil, err
 	}
 
-	tweetResponse, err1 := u.uuc.GetTweetById(tweetId)
+	retweets, err := u.uuc.GetRetweetStats(ctx, viewerId, tweetIds)
 
-	if err1 != nil {
-		respondWithError(w, http.StatusNotFound, "Invalid parameters")
+	if err != nil {
+		return nil, err
 	}
 
-	respondWithJSON(w, http.StatusOK, tweetResponse)
-}
+	annotate := func(tweet domain.Tweet) TweetResponseDTO {
+		return TweetResponseDTO{
+			Tweet:        tweet,
+			LikeCount:    likes[tweet.TweetId].Count,
+			Liked:        likes[tweet.TweetId].Liked,
+			RetweetCount: retweets[tweet.TweetId].Count,
+			Retweeted:    retweets[tweet.TweetId].Retweeted,
+		}
+	}
 
-func (u *UserHttpHandler) GetAllTweets(w http.ResponseWriter, r *http.Request) {
+	responses := make([]TweetResponseDTO, 0, len(tweets))
 
-	// s is a string that contains the value of the author_id query parameter
-	// if it exists, or an empty string if it doesn't
-	authorIdStr := r.URL.Query().Get("author_id")
-	var allTweets []domain.Tweet
+	for _, tweet := range tweets {
+		response := annotate(tweet)
+
+		if referencedTweet, ok := referenced[tweet.ReferencedTweetId]; ok {
+			embedded := annotate(referencedTweet)
+			response.ReferencedTweet = &embedded
+		}
 
-	if authorIdStr == "" {
-		allTweets, _ = u.uuc.GetAllTweets()
-	} else {
-		author_id, _ := strconv.Atoi(authorIdStr)
-		allTweets, _ = u.uuc.GetAuthorTweets(author_id)
+		responses = append(responses, response)
 	}
 
-	sortBy := r.URL.Query().Get("sort")
+	return responses, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp from the query string
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// parsePageRequest reads the limit and cursor query parameters
+func parsePageRequest(r *http.Request) (domain.PageRequest, error) {
+	page := domain.PageRequest{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
 
-	if sortBy == "desc" {
-		sort.Sort(ByTweetIdDesc(allTweets))
-	} else {
-		sort.Sort(ByTweetIdAsc(allTweets))
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return page, errInvalidParameters
+		}
+		page.Limit = limit
 	}
 
-	respondWithJSON(w, http.StatusOK, allTweets)
+	return page, nil
 }
 
 func (u *UserHttpHandler) PolkaWebHooks(w http.ResponseWriter, r *http.Request) {
 
-	apiKey := fetchApiKey(r)
+	apiKey, err := authorizationCredentials(r, "ApiKey")
+
+	if err != nil {
+		respondWithDomainError(w, err)
+		return
+	}
 
-	if apiKey != u.polkaApiKey {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(u.polkaApiKey)) != 1 {
+		respondWithDomainError(w, domain.ErrUnauthenticated)
 		return
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	webHook := WebHookBody{}
-	err := decoder.Decode(&webHook)
+	err = decoder.Decode(&webHook)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters")
+		respondWithDomainError(w, errMalformedBody)
 		return
 	}
 
@@ -417,25 +572,11 @@ func (u *UserHttpHandler) PolkaWebHooks(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if errMembership := u.uuc.UpdateUserMembership(webHook.Data.UserID, true); errMembership != nil {
-		respondWithError(w, http.StatusNotFound, "user not found")
+	if errMembership := u.uuc.UpdateUserMembership(r.Context(), webHook.Data.UserID, true); errMembership != nil {
+		respondWithDomainError(w, errMembership)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, "membership updated")
 
 }
-
-// Define a type for sorting by TweetId in ascending order
-type ByTweetIdAsc []domain.Tweet
-
-func (a ByTweetIdAsc) Len() int           { return len(a) }
-func (a ByTweetIdAsc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByTweetIdAsc) Less(i, j int) bool { return a[i].TweetId < a[j].TweetId }
-
-// Define a type for sorting by TweetId in descending order
-type ByTweetIdDesc []domain.Tweet
-
-func (a ByTweetIdDesc) Len() int           { return len(a) }
-func (a ByTweetIdDesc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByTweetIdDesc) Less(i, j int) bool { return a[i].TweetId > a[j].TweetId }
@@ -0,0 +1,19 @@
+package adapters
+
+import (
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// System clock implementation, reports UTC wall clock time
+func ProvideSystemClock() ports.IClock {
+	return systemClock{}
+}
+
+// systemClock implements ports.IClock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
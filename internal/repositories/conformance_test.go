@@ -0,0 +1,39 @@
+package adapters
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/ports"
+	"github.com/anandh86/chirpy/internal/repositories/repotest"
+)
+
+func TestInMemoryRepoConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) ports.IRepository {
+		return ProvideInMemoryRepo()
+	})
+}
+
+func TestDurableInMemoryRepoConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) ports.IRepository {
+		repo, err := ProvideDurableInMemoryRepo(t.TempDir(), 0)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return repo
+	})
+}
+
+func TestSQLiteRepoConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) ports.IRepository {
+		repo, err := ProvideSQLiteRepo(filepath.Join(t.TempDir(), "chirpy.db"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return repo
+	})
+}
@@ -1,7 +1,12 @@
 package adapters
 
 import (
-	"errors"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/anandh86/chirpy/internal/core/domain"
 	"github.com/anandh86/chirpy/internal/core/ports"
@@ -9,78 +14,426 @@ import (
 
 // In memory implementation
 func ProvideInMemoryRepo() ports.IRepository {
+	return newInMemoryRepo()
+}
+
+func newInMemoryRepo() *myInMemoryRepository {
 	return &myInMemoryRepository{
-		userMap:           make(map[int]domain.User),
-		tweetMap:          make(map[int]domain.Tweet),
-		emaild2idMap:      make(map[string]int),
-		tokenRepo:         make(map[string]bool),
-		currentNoOfUsers:  0,
-		currentNoOfTweets: 0,
+		userMap:      make(map[int]domain.User),
+		tweetMap:     make(map[int]domain.Tweet),
+		authorIndex:  make(map[int][]int),
+		retweets:     make(map[int][]int),
+		replies:      make(map[int][]int),
+		hashtagIndex: make(map[string][]int),
+		followers:    make(map[int][]int),
+		following:    make(map[int][]int),
+		tweetLikers:  make(map[int][]int),
+		userLikes:    make(map[int][]int),
+		emaild2idMap: make(map[string]int),
+		handle2idMap: make(map[string]int),
+		tokenRepo:    make(map[string]domain.RefreshToken),
+		tokenFamily:  make(map[string][]string),
+		userFamilies: make(map[int][]string),
+		accessTokens: make(map[string]domain.AccessToken),
+		accessHashes: make(map[string]string),
+		userAccess:   make(map[int][]string),
+		resets:       make(map[string]domain.PasswordReset),
+		userResets:   make(map[int][]string),
+
+		notifications: newInMemoryNotificationStore(),
 	}
 }
 
-// myInMemoryRepository implements ports.UserRepository
+// myInMemoryRepository implements ports.UserRepository. It is safe for
+// concurrent use: users, tweets and tokens each sit behind their own lock,
+// and IDs are handed out atomically.
 type myInMemoryRepository struct {
+	// usersMu guards userMap, emaild2idMap and handle2idMap
+	usersMu          sync.RWMutex
 	userMap          map[int]domain.User
-	currentNoOfUsers int
+	currentNoOfUsers atomic.Int64
 
+	// tweetsMu guards tweetMap and the ordered tweet indexes
+	tweetsMu          sync.RWMutex
 	tweetMap          map[int]domain.Tweet
-	currentNoOfTweets int
+	currentNoOfTweets atomic.Int64
+
+	// tweet ids in ascending order, overall and per author
+	tweetIndex  []int
+	authorIndex map[int][]int
+
+	// retweet ids in ascending order per retweeted tweet
+	retweets map[int][]int
+
+	// reply ids in ascending order per parent tweet
+	replies map[int][]int
+
+	// tweet ids in ascending order per hashtag
+	hashtagIndex map[string][]int
 
 	emaild2idMap map[string]int
 
-	tokenRepo map[string]bool
+	// handle2idMap is keyed by the lowercased handle
+	handle2idMap map[string]int
+
+	// followsMu guards both directions of the follow graph, each kept as
+	// ascending user ids per user
+	followsMu sync.RWMutex
+	followers map[int][]int
+	following map[int][]int
+
+	// likesMu guards both directions of the likes, each kept as ascending ids:
+	// the users who liked each tweet and the tweets each user liked. When both
+	// are needed, tweetsMu is taken before likesMu.
+	likesMu     sync.RWMutex
+	tweetLikers map[int][]int
+	userLikes   map[int][]int
+
+	// tokensMu guards tokenRepo, tokenFamily with the tokens of every family
+	// and userFamilies with the token families of every user, as well as
+	// accessTokens by id, accessHashes with the id of every hash and
+	// userAccess with the ids of every user's access tokens, and resets by
+	// hash along with userResets, the hashes of every user's password resets
+	tokensMu     sync.RWMutex
+	tokenRepo    map[string]domain.RefreshToken
+	tokenFamily  map[string][]string
+	userFamilies map[int][]string
+	accessTokens map[string]domain.AccessToken
+	accessHashes map[string]string
+	userAccess   map[int][]string
+	resets       map[string]domain.PasswordReset
+	userResets   map[int][]string
+
+	// auditMu guards the audit log, entry ids are their position plus one
+	auditMu  sync.RWMutex
+	auditLog []domain.AuditEntry
+
+	// notifications has a lock of its own
+	notifications *myInMemoryNotificationStore
+}
+
+func (u *myInMemoryRepository) SaveNotification(ctx context.Context, notification domain.Notification) (domain.Notification, error) {
+	return u.notifications.Add(ctx, notification)
+}
+
+func (u *myInMemoryRepository) ListNotifications(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error) {
+	return u.notifications.List(ctx, userId, unreadOnly, beforeId, limit)
+}
+
+func (u *myInMemoryRepository) MarkNotificationsRead(ctx context.Context, userId int, ids []int) error {
+	return u.notifications.MarkRead(ctx, userId, ids)
+}
+
+func (u *myInMemoryRepository) CountUnreadNotifications(ctx context.Context, userId int) (int, error) {
+	return u.notifications.CountUnread(ctx, userId)
+}
+
+func (u *myInMemoryRepository) SaveAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	u.auditMu.Lock()
+	defer u.auditMu.Unlock()
+
+	entry.Id = len(u.auditLog) + 1
+	u.auditLog = append(u.auditLog, entry)
+
+	return entry, nil
+}
+
+func (u *myInMemoryRepository) ListAuditEntries(ctx context.Context, beforeId int, limit int) ([]domain.AuditEntry, error) {
+	u.auditMu.RLock()
+	defer u.auditMu.RUnlock()
+
+	end := len(u.auditLog)
+	if beforeId > 0 && beforeId-1 < end {
+		end = beforeId - 1
+	}
+
+	entries := make([]domain.AuditEntry, 0, min(limit, end))
+
+	for i := end - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, u.auditLog[i])
+	}
+
+	return entries, nil
+}
+
+func (u *myInMemoryRepository) SaveAccessToken(ctx context.Context, token domain.AccessToken) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	return u.addAccessToken(token)
+}
+
+// addAccessToken stores a personal access token, the caller holds tokensMu
+func (u *myInMemoryRepository) addAccessToken(token domain.AccessToken) error {
+	if _, ok := u.accessTokens[token.Id]; ok {
+		return domain.ErrTokenExists
+	}
+
+	if _, ok := u.accessHashes[token.Hash]; ok {
+		return domain.ErrTokenExists
+	}
+
+	token.Scopes = append([]domain.Scope(nil), token.Scopes...)
+	u.accessTokens[token.Id] = token
+	u.accessHashes[token.Hash] = token.Id
+	u.userAccess[token.UserId] = append(u.userAccess[token.UserId], token.Id)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) GetAccessTokenByHash(ctx context.Context, hash string) (domain.AccessToken, error) {
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
+
+	id, ok := u.accessHashes[hash]
+
+	if !ok {
+		return domain.AccessToken{}, domain.ErrTokenNotFound
+	}
+
+	token := u.accessTokens[id]
+	token.Scopes = append([]domain.Scope(nil), token.Scopes...)
+
+	return token, nil
+}
+
+func (u *myInMemoryRepository) ListAccessTokens(ctx context.Context, userId int) ([]domain.AccessToken, error) {
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
+
+	tokens := make([]domain.AccessToken, 0)
+
+	for _, id := range u.userAccess[userId] {
+		token := u.accessTokens[id]
+		token.Scopes = append([]domain.Scope(nil), token.Scopes...)
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].Id < tokens[j].Id
+	})
+
+	return tokens, nil
+}
+
+func (u *myInMemoryRepository) DeleteAccessToken(ctx context.Context, userId int, id string) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	token, ok := u.accessTokens[id]
+
+	if !ok || token.UserId != userId {
+		return domain.ErrTokenNotFound
+	}
+
+	delete(u.accessTokens, id)
+	delete(u.accessHashes, token.Hash)
+
+	ids := u.userAccess[userId]
+	for i, candidate := range ids {
+		if candidate == id {
+			u.userAccess[userId] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (u *myInMemoryRepository) SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	return u.addPasswordReset(reset)
+}
+
+// addPasswordReset stores a password reset, the caller holds tokensMu
+func (u *myInMemoryRepository) addPasswordReset(reset domain.PasswordReset) error {
+	if _, ok := u.resets[reset.Hash]; ok {
+		return domain.ErrTokenExists
+	}
+
+	u.resets[reset.Hash] = reset
+	u.userResets[reset.UserId] = append(u.userResets[reset.UserId], reset.Hash)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) UsePasswordReset(ctx context.Context, hash string, now time.Time) (domain.PasswordReset, error) {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	reset, ok := u.resets[hash]
+
+	if !ok {
+		return domain.PasswordReset{}, domain.ErrTokenNotFound
+	}
+
+	if reset.Used {
+		return domain.PasswordReset{}, domain.ErrTokenUsed
+	}
+
+	if !now.Before(reset.ExpiresAt) {
+		return domain.PasswordReset{}, domain.ErrTokenExpired
+	}
+
+	for _, other := range u.userResets[reset.UserId] {
+		used := u.resets[other]
+		used.Used = true
+		u.resets[other] = used
+	}
+
+	return reset, nil
+}
+
+func (u *myInMemoryRepository) SaveRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	return u.addRefreshToken(token)
+}
+
+// addRefreshToken stores a token, the caller holds tokensMu
+func (u *myInMemoryRepository) addRefreshToken(token domain.RefreshToken) error {
+	if _, ok := u.tokenRepo[token.Token]; ok {
+		return domain.ErrTokenExists
+	}
+
+	if len(u.tokenFamily[token.FamilyId]) == 0 {
+		u.userFamilies[token.UserId] = append(u.userFamilies[token.UserId], token.FamilyId)
+	}
+
+	u.tokenRepo[token.Token] = token
+	u.tokenFamily[token.FamilyId] = append(u.tokenFamily[token.FamilyId], token.Token)
+	return nil
+}
+
+func (u *myInMemoryRepository) GetRefreshToken(ctx context.Context, token string) (domain.RefreshToken, error) {
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
+
+	stored, ok := u.tokenRepo[token]
+
+	if !ok {
+		return stored, domain.ErrTokenNotFound
+	}
+
+	return stored, nil
+}
+
+func (u *myInMemoryRepository) RotateRefreshToken(ctx context.Context, token string, next domain.RefreshToken) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+
+	current, ok := u.tokenRepo[token]
+
+	if !ok {
+		return domain.ErrTokenNotFound
+	}
+
+	if current.Rotated || current.Revoked {
+		return domain.ErrTokenRotated
+	}
+
+	if err := u.addRefreshToken(next); err != nil {
+		return err
+	}
+
+	current.Rotated = true
+	u.tokenRepo[token] = current
+	return nil
 }
 
-func (u *myInMemoryRepository) CreateToken(token string) bool {
+func (u *myInMemoryRepository) RevokeTokenFamily(ctx context.Context, familyId string) error {
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
 
-	if _, ok := u.tokenRepo[token]; ok {
-		return false
+	for _, token := range u.tokenFamily[familyId] {
+		stored := u.tokenRepo[token]
+		stored.Revoked = true
+		u.tokenRepo[token] = stored
 	}
 
-	u.tokenRepo[token] = false
-	return true
+	return nil
 }
 
-func (u *myInMemoryRepository) ReadToken(token string) bool {
+func (u *myInMemoryRepository) ListRefreshTokens(ctx context.Context, userId int) ([]domain.RefreshToken, error) {
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
 
-	return u.tokenRepo[token]
+	tokens := make([]domain.RefreshToken, 0)
+
+	for _, familyId := range u.userFamilies[userId] {
+		for _, token := range u.tokenFamily[familyId] {
+			if stored := u.tokenRepo[token]; !stored.Rotated && !stored.Revoked {
+				tokens = append(tokens, stored)
+			}
+		}
+	}
+
+	return tokens, nil
 }
 
-func (u *myInMemoryRepository) UpdateToken(token string, revokeStatus bool) bool {
+func (u *myInMemoryRepository) GetLatestRefreshToken(ctx context.Context, familyId string) (domain.RefreshToken, error) {
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
+
+	family := u.tokenFamily[familyId]
 
-	if _, ok := u.tokenRepo[token]; !ok {
-		return false
+	if len(family) == 0 {
+		return domain.RefreshToken{}, domain.ErrTokenNotFound
 	}
 
-	u.tokenRepo[token] = revokeStatus
+	// rotating appends the successor, the latest token comes last
+	latest := u.tokenRepo[family[len(family)-1]]
 
-	return true
+	if latest.Rotated || latest.Revoked {
+		return domain.RefreshToken{}, domain.ErrTokenNotFound
+	}
+
+	return latest, nil
 }
 
-func (u *myInMemoryRepository) Save(user domain.User) (domain.User, error) {
+func (u *myInMemoryRepository) Save(ctx context.Context, user domain.User) (domain.User, error) {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
 
 	if _, ok := u.emaild2idMap[user.Email]; ok {
 		// user already present
-		return user, errors.ErrUnsupported
+		return user, domain.ErrEmailTaken
 	}
 
-	userId := u.currentNoOfUsers + 1
-	u.currentNoOfUsers = userId
+	if _, ok := u.handle2idMap[handleKey(user.Handle)]; ok {
+		return user, domain.ErrHandleTaken
+	}
+
+	userId := int(u.currentNoOfUsers.Add(1))
 	user.ID = userId
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
 
 	u.userMap[userId] = user
 	u.emaild2idMap[user.Email] = userId
+	if user.Handle != "" {
+		u.handle2idMap[handleKey(user.Handle)] = userId
+	}
 
 	return user, nil
 }
 
-func (u *myInMemoryRepository) UpdateUserMembership(id int, isMember bool) error {
+func (u *myInMemoryRepository) UpdateUserMembership(ctx context.Context, id int, isMember bool) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
 	// for valid item, update the data structures
 	dbUser, ok := u.userMap[id]
 
 	if !ok {
-		return errors.ErrUnsupported
+		return domain.ErrUserNotFound
 	}
 
 	dbUser.IsChirpyRed = isMember
@@ -88,100 +441,547 @@ func (u *myInMemoryRepository) UpdateUserMembership(id int, isMember bool) error
 	return nil
 }
 
-func (u *myInMemoryRepository) UpdateUser(id int, user domain.User) error {
+func (u *myInMemoryRepository) UpdateUserRole(ctx context.Context, id int, role domain.Role) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
+	dbUser, ok := u.userMap[id]
+
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	dbUser.Role = role
+	u.userMap[id] = dbUser
+	return nil
+}
+
+func (u *myInMemoryRepository) UpdateUserSuspension(ctx context.Context, id int, suspended bool) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
+	dbUser, ok := u.userMap[id]
+
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	dbUser.Suspended = suspended
+	u.userMap[id] = dbUser
+	return nil
+}
+
+func (u *myInMemoryRepository) UpdateUser(ctx context.Context, id int, user domain.User) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
 	// for valid item, update the data structures
 	dbUser, ok := u.userMap[id]
 
 	if !ok {
-		return errors.ErrUnsupported
+		return domain.ErrUserNotFound
 	}
 
 	if user.Email != "" && user.Email != dbUser.Email {
+		if _, ok := u.emaild2idMap[user.Email]; ok {
+			return domain.ErrEmailTaken
+		}
+
 		// delete old email
 		delete(u.emaild2idMap, dbUser.Email)
 		dbUser.Email = user.Email
 		u.emaild2idMap[user.Email] = id
 	}
 
-	user.ID = id
-	u.userMap[id] = user
+	if len(user.HashedPassword) > 0 {
+		dbUser.HashedPassword = user.HashedPassword
+	}
+
+	dbUser.UpdatedAt = user.UpdatedAt
+	u.userMap[id] = dbUser
 	return nil
 }
 
-func (u *myInMemoryRepository) GetUserById(id int) (domain.User, error) {
+func (u *myInMemoryRepository) UpdateProfile(ctx context.Context, id int, user domain.User) error {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+
+	dbUser, ok := u.userMap[id]
+
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+
+	newKey, oldKey := handleKey(user.Handle), handleKey(dbUser.Handle)
+
+	if owner, ok := u.handle2idMap[newKey]; ok && owner != id {
+		return domain.ErrHandleTaken
+	}
+
+	if dbUser.Handle != "" {
+		delete(u.handle2idMap, oldKey)
+	}
+	if user.Handle != "" {
+		u.handle2idMap[newKey] = id
+	}
+
+	dbUser.Profile = user.Profile
+	dbUser.UpdatedAt = user.UpdatedAt
+	u.userMap[id] = dbUser
+	return nil
+}
+
+func (u *myInMemoryRepository) GetUserById(ctx context.Context, id int) (domain.User, error) {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
+
 	user, ok := u.userMap[id]
 
 	if !ok {
-		return user, errors.New("user id not found")
+		return user, domain.ErrUserNotFound
 	}
 
 	return user, nil
 }
 
-func (u *myInMemoryRepository) GetUserId(emailid string) (int, error) {
+func (u *myInMemoryRepository) GetUserId(ctx context.Context, emailid string) (int, error) {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
 
 	userId, ok := u.emaild2idMap[emailid]
 
 	if !ok {
 		// user not present
-		return 0, errors.ErrUnsupported
+		return 0, domain.ErrUserNotFound
+	}
+
+	return userId, nil
+}
+
+func (u *myInMemoryRepository) GetUserIdByHandle(ctx context.Context, handle string) (int, error) {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
+
+	userId, ok := u.handle2idMap[handleKey(handle)]
+
+	if !ok {
+		return 0, domain.ErrUserNotFound
 	}
 
 	return userId, nil
 }
 
-func (u *myInMemoryRepository) SaveTweet(tweet domain.Tweet) (domain.Tweet, error) {
-	tweetID := u.currentNoOfTweets + 1
-	u.currentNoOfTweets = tweetID
+// handles are unique ignoring case, so they are indexed lowercased
+func handleKey(handle string) string {
+	return strings.ToLower(handle)
+}
+
+func (u *myInMemoryRepository) SaveTweet(ctx context.Context, tweet domain.Tweet) (domain.Tweet, error) {
+	u.tweetsMu.Lock()
+	defer u.tweetsMu.Unlock()
+
+	if tweet.Kind == "" {
+		tweet.Kind = domain.TweetKindOriginal
+	}
+
+	if tweet.Kind == domain.TweetKindRetweet && u.retweetedBy(tweet.ReferencedTweetId, tweet.AuthorId) {
+		return tweet, domain.ErrAlreadyRetweeted
+	}
+
+	tweetID := int(u.currentNoOfTweets.Add(1))
 	tweet.TweetId = tweetID
+
+	if tweet.ConversationId == 0 {
+		tweet.ConversationId = tweetID
+	}
+
 	u.tweetMap[tweetID] = tweet
+	u.tweetIndex = insertSorted(u.tweetIndex, tweetID)
+	u.authorIndex[tweet.AuthorId] = insertSorted(u.authorIndex[tweet.AuthorId], tweetID)
+
+	if tweet.Kind == domain.TweetKindRetweet {
+		u.retweets[tweet.ReferencedTweetId] = insertSorted(u.retweets[tweet.ReferencedTweetId], tweetID)
+	}
+
+	if tweet.InReplyTo != 0 {
+		u.replies[tweet.InReplyTo] = insertSorted(u.replies[tweet.InReplyTo], tweetID)
+	}
+
+	for _, tag := range tweet.Hashtags {
+		u.hashtagIndex[tag] = insertSorted(u.hashtagIndex[tag], tweetID)
+	}
 
 	return tweet, nil
 }
 
-func (u *myInMemoryRepository) DeleteTweet(tweet domain.Tweet) error {
+func (u *myInMemoryRepository) DeleteTweet(ctx context.Context, tweet domain.Tweet) error {
+	u.tweetsMu.Lock()
+	defer u.tweetsMu.Unlock()
+
 	tweetID := tweet.TweetId
 
 	if _, ok := u.tweetMap[tweetID]; !ok {
 		// tweet not present
-		return errors.ErrUnsupported
+		return domain.ErrTweetNotFound
 	}
 
-	delete(u.tweetMap, tweetID)
+	u.likesMu.Lock()
+	defer u.likesMu.Unlock()
+
+	// retweets go with the tweet, quotes stay
+	retweetIds := u.retweets[tweetID]
+	delete(u.retweets, tweetID)
+
+	for _, retweetId := range retweetIds {
+		u.removeTweet(retweetId)
+	}
+
+	u.removeTweet(tweetID)
 
 	return nil
 }
 
-func (u *myInMemoryRepository) GetTweetById(id int) (domain.Tweet, error) {
+// removeTweet drops a stored tweet and its likes from every index. Callers
+// hold tweetsMu and likesMu.
+func (u *myInMemoryRepository) removeTweet(tweetID int) {
+	stored := u.tweetMap[tweetID]
+
+	delete(u.tweetMap, tweetID)
+	u.tweetIndex = removeSorted(u.tweetIndex, tweetID)
+	u.authorIndex[stored.AuthorId] = removeSorted(u.authorIndex[stored.AuthorId], tweetID)
+
+	if retweetIds, ok := u.retweets[stored.ReferencedTweetId]; ok && stored.Kind == domain.TweetKindRetweet {
+		u.retweets[stored.ReferencedTweetId] = removeSorted(retweetIds, tweetID)
+	}
+
+	// replies to the tweet stay, still pointing at it
+	if replyIds, ok := u.replies[stored.InReplyTo]; ok {
+		u.replies[stored.InReplyTo] = removeSorted(replyIds, tweetID)
+	}
+
+	for _, tag := range stored.Hashtags {
+		if tagged := removeSorted(u.hashtagIndex[tag], tweetID); len(tagged) > 0 {
+			u.hashtagIndex[tag] = tagged
+		} else {
+			delete(u.hashtagIndex, tag)
+		}
+	}
+
+	for _, userId := range u.tweetLikers[tweetID] {
+		u.userLikes[userId] = removeSorted(u.userLikes[userId], tweetID)
+	}
+	delete(u.tweetLikers, tweetID)
+}
+
+// retweetedBy reports whether the user retweeted the tweet. Callers hold tweetsMu.
+func (u *myInMemoryRepository) retweetedBy(tweetID int, userId int) bool {
+	for _, retweetId := range u.retweets[tweetID] {
+		if u.tweetMap[retweetId].AuthorId == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *myInMemoryRepository) GetTweetById(ctx context.Context, id int) (domain.Tweet, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
 	tweet, ok := u.tweetMap[id]
 
 	if !ok {
-		return tweet, errors.New("tweet id not found")
+		return tweet, domain.ErrTweetNotFound
 	}
 
 	return tweet, nil
 }
 
-func (u *myInMemoryRepository) FetchAllTweets() ([]domain.Tweet, error) {
-	tweets := make([]domain.Tweet, 0)
+func (u *myInMemoryRepository) ListTweets(ctx context.Context, filter domain.TweetFilter, afterId int, limit int) ([]domain.Tweet, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	ids := u.tweetIndex
 
-	for _, tweet := range u.tweetMap {
-		tweets = append(tweets, tweet)
+	if filter.AuthorId != 0 {
+		ids = u.authorIndex[filter.AuthorId]
 	}
 
-	return tweets, nil
+	if filter.Since.IsZero() && filter.Until.IsZero() {
+		tweets := make([]domain.Tweet, 0, limit)
+
+		for _, id := range pageIds(ids, filter.Descending, afterId, limit) {
+			tweets = append(tweets, u.tweetMap[id])
+		}
+
+		return tweets, nil
+	}
+
+	return u.listTweetsInWindow(ctx, ids, filter, afterId, limit)
 }
 
-func (u *myInMemoryRepository) FetchAuthorTweets(author_id int) ([]domain.Tweet, error) {
+// listTweetsInWindow walks the index from the cursor keeping tweets inside the
+// time window. Ids are handed out in creation order, so the walk can binary
+// search to the near end of the window and stop once it steps past the far end.
+func (u *myInMemoryRepository) listTweetsInWindow(ctx context.Context, ids []int, filter domain.TweetFilter, afterId int, limit int) ([]domain.Tweet, error) {
+	if !filter.Descending && !filter.Since.IsZero() {
+		// skip straight past everything older than the window
+		i := sort.Search(len(ids), func(i int) bool { return !u.tweetMap[ids[i]].CreatedAt.Before(filter.Since) })
+		if i > 0 && ids[i-1] > afterId {
+			afterId = ids[i-1]
+		}
+	}
+
+	if filter.Descending && !filter.Until.IsZero() {
+		// skip straight past everything newer than the window
+		i := sort.Search(len(ids), func(i int) bool { return !u.tweetMap[ids[i]].CreatedAt.Before(filter.Until) })
+		if i < len(ids) && (afterId == 0 || ids[i] < afterId) {
+			afterId = ids[i]
+		}
+	}
+
+	tweets := make([]domain.Tweet, 0, limit)
+
+	for len(tweets) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-	tweets := make([]domain.Tweet, 0)
+		batch := pageIds(ids, filter.Descending, afterId, limit)
 
-	for _, tweet := range u.tweetMap {
-		if tweet.AuthorId != author_id {
-			continue
+		if len(batch) == 0 {
+			return tweets, nil
 		}
-		tweets = append(tweets, tweet)
+
+		for _, id := range batch {
+			tweet := u.tweetMap[id]
+
+			if filter.Descending && !filter.Since.IsZero() && tweet.CreatedAt.Before(filter.Since) {
+				return tweets, nil
+			}
+
+			if !filter.Descending && !filter.Until.IsZero() && !tweet.CreatedAt.Before(filter.Until) {
+				return tweets, nil
+			}
+
+			if filter.Matches(tweet) && len(tweets) < limit {
+				tweets = append(tweets, tweet)
+			}
+		}
+
+		afterId = batch[len(batch)-1]
 	}
 
 	return tweets, nil
 }
+
+func (u *myInMemoryRepository) ListReplies(ctx context.Context, tweetId int, afterId int, limit int) ([]domain.Tweet, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	replyIds := pageIds(u.replies[tweetId], false, afterId, limit)
+	replies := make([]domain.Tweet, 0, len(replyIds))
+
+	for _, id := range replyIds {
+		replies = append(replies, u.tweetMap[id])
+	}
+
+	return replies, nil
+}
+
+func (u *myInMemoryRepository) CountReplies(ctx context.Context, tweetIds []int) (map[int]int, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	counts := make(map[int]int, len(tweetIds))
+
+	for _, tweetId := range tweetIds {
+		counts[tweetId] = len(u.replies[tweetId])
+	}
+
+	return counts, nil
+}
+
+func (u *myInMemoryRepository) ListTweetsByHashtag(ctx context.Context, tag string, beforeId int, limit int) ([]domain.Tweet, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	tweetIds := pageIds(u.hashtagIndex[tag], true, beforeId, limit)
+	tweets := make([]domain.Tweet, 0, len(tweetIds))
+
+	for _, id := range tweetIds {
+		tweets = append(tweets, u.tweetMap[id])
+	}
+
+	return tweets, nil
+}
+
+func (u *myInMemoryRepository) HashtagUses(ctx context.Context, since time.Time) ([]domain.HashtagUse, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	uses := make([]domain.HashtagUse, 0)
+
+	// ids are handed out in creation order, walk back from the newest tweet
+	// until the window is left behind
+	for i := len(u.tweetIndex) - 1; i >= 0; i-- {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		tweet := u.tweetMap[u.tweetIndex[i]]
+
+		if tweet.CreatedAt.Before(since) {
+			break
+		}
+
+		for _, tag := range tweet.Hashtags {
+			uses = append(uses, domain.HashtagUse{Tag: tag, CreatedAt: tweet.CreatedAt})
+		}
+	}
+
+	return uses, nil
+}
+
+func (u *myInMemoryRepository) Follow(ctx context.Context, followerId int, followeeId int) error {
+	u.followsMu.Lock()
+	defer u.followsMu.Unlock()
+
+	if containsSorted(u.following[followerId], followeeId) {
+		return domain.ErrAlreadyFollowing
+	}
+
+	u.following[followerId] = insertSorted(u.following[followerId], followeeId)
+	u.followers[followeeId] = insertSorted(u.followers[followeeId], followerId)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) Unfollow(ctx context.Context, followerId int, followeeId int) error {
+	u.followsMu.Lock()
+	defer u.followsMu.Unlock()
+
+	if !containsSorted(u.following[followerId], followeeId) {
+		return domain.ErrNotFollowing
+	}
+
+	u.following[followerId] = removeSorted(u.following[followerId], followeeId)
+	u.followers[followeeId] = removeSorted(u.followers[followeeId], followerId)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) ListFollowers(ctx context.Context, userId int, afterId int, limit int) ([]int, error) {
+	u.followsMu.RLock()
+	defer u.followsMu.RUnlock()
+
+	return pageIds(u.followers[userId], false, afterId, limit), nil
+}
+
+func (u *myInMemoryRepository) ListFollowing(ctx context.Context, userId int, afterId int, limit int) ([]int, error) {
+	u.followsMu.RLock()
+	defer u.followsMu.RUnlock()
+
+	return pageIds(u.following[userId], false, afterId, limit), nil
+}
+
+func (u *myInMemoryRepository) CountFollows(ctx context.Context, userId int) (domain.FollowStats, error) {
+	u.followsMu.RLock()
+	defer u.followsMu.RUnlock()
+
+	return domain.FollowStats{
+		Followers: len(u.followers[userId]),
+		Following: len(u.following[userId]),
+	}, nil
+}
+
+func (u *myInMemoryRepository) LikeTweet(ctx context.Context, userId int, tweetId int) error {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	if _, ok := u.tweetMap[tweetId]; !ok {
+		return domain.ErrTweetNotFound
+	}
+
+	u.likesMu.Lock()
+	defer u.likesMu.Unlock()
+
+	if containsSorted(u.userLikes[userId], tweetId) {
+		return domain.ErrAlreadyLiked
+	}
+
+	u.userLikes[userId] = insertSorted(u.userLikes[userId], tweetId)
+	u.tweetLikers[tweetId] = insertSorted(u.tweetLikers[tweetId], userId)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) UnlikeTweet(ctx context.Context, userId int, tweetId int) error {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	if _, ok := u.tweetMap[tweetId]; !ok {
+		return domain.ErrTweetNotFound
+	}
+
+	u.likesMu.Lock()
+	defer u.likesMu.Unlock()
+
+	if !containsSorted(u.userLikes[userId], tweetId) {
+		return domain.ErrNotLiked
+	}
+
+	u.userLikes[userId] = removeSorted(u.userLikes[userId], tweetId)
+	u.tweetLikers[tweetId] = removeSorted(u.tweetLikers[tweetId], userId)
+
+	return nil
+}
+
+func (u *myInMemoryRepository) ListTweetLikers(ctx context.Context, tweetId int, afterId int, limit int) ([]int, error) {
+	u.likesMu.RLock()
+	defer u.likesMu.RUnlock()
+
+	return pageIds(u.tweetLikers[tweetId], false, afterId, limit), nil
+}
+
+func (u *myInMemoryRepository) ListUserLikes(ctx context.Context, userId int, beforeId int, limit int) ([]int, error) {
+	u.likesMu.RLock()
+	defer u.likesMu.RUnlock()
+
+	return pageIds(u.userLikes[userId], true, beforeId, limit), nil
+}
+
+func (u *myInMemoryRepository) LikeStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.LikeStats, error) {
+	u.likesMu.RLock()
+	defer u.likesMu.RUnlock()
+
+	stats := make(map[int]domain.LikeStats, len(tweetIds))
+
+	for _, tweetId := range tweetIds {
+		likers := u.tweetLikers[tweetId]
+		stats[tweetId] = domain.LikeStats{
+			Count: len(likers),
+			Liked: viewerId != 0 && containsSorted(likers, viewerId),
+		}
+	}
+
+	return stats, nil
+}
+
+func (u *myInMemoryRepository) RetweetStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.RetweetStats, error) {
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+
+	stats := make(map[int]domain.RetweetStats, len(tweetIds))
+
+	for _, tweetId := range tweetIds {
+		stats[tweetId] = domain.RetweetStats{
+			Count:     len(u.retweets[tweetId]),
+			Retweeted: viewerId != 0 && u.retweetedBy(tweetId, viewerId),
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,836 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+// Mutation names recorded in the write-ahead log
+const (
+	opSave                 = "save"
+	opUpdateUser           = "update_user"
+	opUpdateUserMembership = "update_user_membership"
+	opUpdateProfile        = "update_profile"
+	opSaveTweet            = "save_tweet"
+	opDeleteTweet          = "delete_tweet"
+	opFollow               = "follow"
+	opUnfollow             = "unfollow"
+	opLike                 = "like"
+	opUnlike               = "unlike"
+	opSaveRefreshToken     = "save_refresh_token"
+	opRotateRefreshToken   = "rotate_refresh_token"
+	opRevokeTokenFamily    = "revoke_token_family"
+	opSaveAccessToken      = "save_access_token"
+	opDeleteAccessToken    = "delete_access_token"
+	opUpdateUserRole       = "update_user_role"
+	opUpdateUserSuspension = "update_user_suspension"
+	opSaveAuditEntry       = "save_audit_entry"
+	opSavePasswordReset    = "save_password_reset"
+	opUsePasswordReset     = "use_password_reset"
+	opSaveNotification     = "save_notification"
+	opReadNotifications    = "mark_notifications_read"
+
+	// logged before refresh tokens had families, replaying them is a no-op
+	opCreateToken = "create_token"
+	opUpdateToken = "update_token"
+)
+
+// walRecord is one line of the write-ahead log
+type walRecord struct {
+	Seq      uint64        `json:"seq"`
+	Op       string        `json:"op"`
+	Id       int           `json:"id,omitempty"`
+	User     *domain.User  `json:"user,omitempty"`
+	Tweet    *domain.Tweet `json:"tweet,omitempty"`
+	IsMember bool          `json:"is_member,omitempty"`
+	TargetId int           `json:"target_id,omitempty"`
+	Token    string        `json:"token,omitempty"`
+
+	RefreshToken *domain.RefreshToken `json:"refresh_token,omitempty"`
+	FamilyId     string               `json:"family_id,omitempty"`
+
+	AccessToken   *domain.AccessToken `json:"access_token,omitempty"`
+	AccessTokenId string              `json:"access_token_id,omitempty"`
+
+	Role       domain.Role        `json:"role,omitempty"`
+	Suspended  bool               `json:"suspended,omitempty"`
+	AuditEntry *domain.AuditEntry `json:"audit_entry,omitempty"`
+
+	PasswordReset *domain.PasswordReset `json:"password_reset,omitempty"`
+	// At is the time an operation that depends on it ran at
+	At *time.Time `json:"at,omitempty"`
+
+	Notification    *domain.Notification `json:"notification,omitempty"`
+	NotificationIds []int                `json:"notification_ids,omitempty"`
+}
+
+// inMemoryState is everything needed to rebuild a myInMemoryRepository
+type inMemoryState struct {
+	LastSeq           uint64                          `json:"last_seq"`
+	UserMap           map[int]domain.User             `json:"users"`
+	CurrentNoOfUsers  int                             `json:"current_no_of_users"`
+	TweetMap          map[int]domain.Tweet            `json:"tweets"`
+	CurrentNoOfTweets int                             `json:"current_no_of_tweets"`
+	Emaild2idMap      map[string]int                  `json:"email_to_id"`
+	RefreshTokens     map[string]domain.RefreshToken  `json:"refresh_tokens"`
+	AccessTokens      map[string]domain.AccessToken   `json:"access_tokens"`
+	PasswordResets    map[string]domain.PasswordReset `json:"password_resets"`
+	AuditLog          []domain.AuditEntry             `json:"audit_log"`
+	Following         map[int][]int                   `json:"following"`
+	Likes             map[int][]int                   `json:"likes"`
+	// Notifications are per user in ascending id order
+	Notifications      map[int][]domain.Notification `json:"notifications"`
+	LastNotificationId int                           `json:"last_notification_id"`
+}
+
+// Durable in memory implementation. Every mutation is appended to a log in dir
+// before it is applied, and the log is compacted into a snapshot every
+// snapshotInterval. Both are replayed on startup.
+func ProvideDurableInMemoryRepo(dir string, snapshotInterval time.Duration) (ports.IRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &myDurableInMemoryRepository{
+		myInMemoryRepository: newInMemoryRepo(),
+		dir:                  dir,
+	}
+
+	if err := d.recover(); err != nil {
+		return nil, err
+	}
+
+	if snapshotInterval > 0 {
+		go d.snapshotLoop(snapshotInterval)
+	}
+
+	return d, nil
+}
+
+// myDurableInMemoryRepository implements ports.IRepository. Reads go straight
+// to the embedded in-memory repository, mutations are logged first.
+type myDurableInMemoryRepository struct {
+	*myInMemoryRepository
+
+	// mu serializes mutations so the log order matches the apply order
+	mu      sync.Mutex
+	dir     string
+	wal     *os.File
+	lastSeq uint64
+}
+
+func (d *myDurableInMemoryRepository) Save(ctx context.Context, user domain.User) (domain.User, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSave, User: &user}); err != nil {
+		return user, err
+	}
+
+	return d.myInMemoryRepository.Save(ctx, user)
+}
+
+func (d *myDurableInMemoryRepository) UpdateUser(ctx context.Context, id int, user domain.User) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUpdateUser, Id: id, User: &user}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UpdateUser(ctx, id, user)
+}
+
+func (d *myDurableInMemoryRepository) UpdateProfile(ctx context.Context, id int, user domain.User) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUpdateProfile, Id: id, User: &user}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UpdateProfile(ctx, id, user)
+}
+
+func (d *myDurableInMemoryRepository) UpdateUserMembership(ctx context.Context, id int, isMember bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUpdateUserMembership, Id: id, IsMember: isMember}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UpdateUserMembership(ctx, id, isMember)
+}
+
+func (d *myDurableInMemoryRepository) UpdateUserRole(ctx context.Context, id int, role domain.Role) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUpdateUserRole, Id: id, Role: role}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UpdateUserRole(ctx, id, role)
+}
+
+func (d *myDurableInMemoryRepository) UpdateUserSuspension(ctx context.Context, id int, suspended bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUpdateUserSuspension, Id: id, Suspended: suspended}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UpdateUserSuspension(ctx, id, suspended)
+}
+
+func (d *myDurableInMemoryRepository) SaveTweet(ctx context.Context, tweet domain.Tweet) (domain.Tweet, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSaveTweet, Tweet: &tweet}); err != nil {
+		return tweet, err
+	}
+
+	return d.myInMemoryRepository.SaveTweet(ctx, tweet)
+}
+
+func (d *myDurableInMemoryRepository) DeleteTweet(ctx context.Context, tweet domain.Tweet) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opDeleteTweet, Tweet: &tweet}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.DeleteTweet(ctx, tweet)
+}
+
+func (d *myDurableInMemoryRepository) Follow(ctx context.Context, followerId int, followeeId int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opFollow, Id: followerId, TargetId: followeeId}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.Follow(ctx, followerId, followeeId)
+}
+
+func (d *myDurableInMemoryRepository) Unfollow(ctx context.Context, followerId int, followeeId int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUnfollow, Id: followerId, TargetId: followeeId}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.Unfollow(ctx, followerId, followeeId)
+}
+
+func (d *myDurableInMemoryRepository) LikeTweet(ctx context.Context, userId int, tweetId int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opLike, Id: userId, TargetId: tweetId}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.LikeTweet(ctx, userId, tweetId)
+}
+
+func (d *myDurableInMemoryRepository) UnlikeTweet(ctx context.Context, userId int, tweetId int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUnlike, Id: userId, TargetId: tweetId}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.UnlikeTweet(ctx, userId, tweetId)
+}
+
+func (d *myDurableInMemoryRepository) SaveRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSaveRefreshToken, RefreshToken: &token}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.SaveRefreshToken(ctx, token)
+}
+
+func (d *myDurableInMemoryRepository) RotateRefreshToken(ctx context.Context, token string, next domain.RefreshToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opRotateRefreshToken, Token: token, RefreshToken: &next}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.RotateRefreshToken(ctx, token, next)
+}
+
+func (d *myDurableInMemoryRepository) RevokeTokenFamily(ctx context.Context, familyId string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opRevokeTokenFamily, FamilyId: familyId}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.RevokeTokenFamily(ctx, familyId)
+}
+
+func (d *myDurableInMemoryRepository) SaveAccessToken(ctx context.Context, token domain.AccessToken) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSaveAccessToken, AccessToken: &token}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.SaveAccessToken(ctx, token)
+}
+
+func (d *myDurableInMemoryRepository) DeleteAccessToken(ctx context.Context, userId int, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opDeleteAccessToken, Id: userId, AccessTokenId: id}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.DeleteAccessToken(ctx, userId, id)
+}
+
+func (d *myDurableInMemoryRepository) SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSavePasswordReset, PasswordReset: &reset}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.SavePasswordReset(ctx, reset)
+}
+
+func (d *myDurableInMemoryRepository) UsePasswordReset(ctx context.Context, hash string, now time.Time) (domain.PasswordReset, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opUsePasswordReset, Token: hash, At: &now}); err != nil {
+		return domain.PasswordReset{}, err
+	}
+
+	return d.myInMemoryRepository.UsePasswordReset(ctx, hash, now)
+}
+
+func (d *myDurableInMemoryRepository) SaveAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSaveAuditEntry, AuditEntry: &entry}); err != nil {
+		return entry, err
+	}
+
+	return d.myInMemoryRepository.SaveAuditEntry(ctx, entry)
+}
+
+func (d *myDurableInMemoryRepository) SaveNotification(ctx context.Context, notification domain.Notification) (domain.Notification, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opSaveNotification, Notification: &notification}); err != nil {
+		return notification, err
+	}
+
+	return d.myInMemoryRepository.SaveNotification(ctx, notification)
+}
+
+func (d *myDurableInMemoryRepository) MarkNotificationsRead(ctx context.Context, userId int, ids []int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.append(walRecord{Op: opReadNotifications, Id: userId, NotificationIds: ids}); err != nil {
+		return err
+	}
+
+	return d.myInMemoryRepository.MarkNotificationsRead(ctx, userId, ids)
+}
+
+// append writes the record to the log and syncs it to disk. Callers hold d.mu.
+func (d *myDurableInMemoryRepository) append(record walRecord) error {
+	record.Seq = d.lastSeq + 1
+
+	line, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.wal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if err := d.wal.Sync(); err != nil {
+		return err
+	}
+
+	d.lastSeq = record.Seq
+	return nil
+}
+
+// apply replays a logged mutation against the in-memory state. Mutations are
+// deterministic, so one that failed when it was logged fails the same way here.
+func (d *myDurableInMemoryRepository) apply(record walRecord) error {
+	inner := d.myInMemoryRepository
+	ctx := context.Background()
+
+	switch record.Op {
+	case opSave:
+		inner.Save(ctx, *record.User)
+	case opUpdateUser:
+		inner.UpdateUser(ctx, record.Id, *record.User)
+	case opUpdateUserMembership:
+		inner.UpdateUserMembership(ctx, record.Id, record.IsMember)
+	case opUpdateProfile:
+		inner.UpdateProfile(ctx, record.Id, *record.User)
+	case opSaveTweet:
+		inner.SaveTweet(ctx, *record.Tweet)
+	case opDeleteTweet:
+		inner.DeleteTweet(ctx, *record.Tweet)
+	case opFollow:
+		inner.Follow(ctx, record.Id, record.TargetId)
+	case opUnfollow:
+		inner.Unfollow(ctx, record.Id, record.TargetId)
+	case opLike:
+		inner.LikeTweet(ctx, record.Id, record.TargetId)
+	case opUnlike:
+		inner.UnlikeTweet(ctx, record.Id, record.TargetId)
+	case opSaveRefreshToken:
+		inner.SaveRefreshToken(ctx, *record.RefreshToken)
+	case opRotateRefreshToken:
+		inner.RotateRefreshToken(ctx, record.Token, *record.RefreshToken)
+	case opRevokeTokenFamily:
+		inner.RevokeTokenFamily(ctx, record.FamilyId)
+	case opSaveAccessToken:
+		inner.SaveAccessToken(ctx, *record.AccessToken)
+	case opDeleteAccessToken:
+		inner.DeleteAccessToken(ctx, record.Id, record.AccessTokenId)
+	case opUpdateUserRole:
+		inner.UpdateUserRole(ctx, record.Id, record.Role)
+	case opUpdateUserSuspension:
+		inner.UpdateUserSuspension(ctx, record.Id, record.Suspended)
+	case opSaveAuditEntry:
+		inner.SaveAuditEntry(ctx, *record.AuditEntry)
+	case opSavePasswordReset:
+		inner.SavePasswordReset(ctx, *record.PasswordReset)
+	case opUsePasswordReset:
+		// records from before uses were timed were all of live resets
+		var at time.Time
+
+		if record.At != nil {
+			at = *record.At
+		}
+
+		inner.UsePasswordReset(ctx, record.Token, at)
+	case opSaveNotification:
+		inner.SaveNotification(ctx, *record.Notification)
+	case opReadNotifications:
+		inner.MarkNotificationsRead(ctx, record.Id, record.NotificationIds)
+	case opCreateToken, opUpdateToken:
+		// tokens without a family can't be rotated, their users log in again
+	default:
+		return errors.New("unknown write-ahead log operation " + record.Op)
+	}
+
+	return nil
+}
+
+// recover loads the latest snapshot, replays the log on top of it and opens
+// the log for appending
+func (d *myDurableInMemoryRepository) recover() error {
+	if err := d.loadSnapshot(); err != nil {
+		return err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(d.dir, walFileName), os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	validSize, err := d.replay(wal)
+
+	if err != nil {
+		wal.Close()
+		return err
+	}
+
+	// drop a torn record left behind by a crash mid-write
+	if err := wal.Truncate(validSize); err != nil {
+		wal.Close()
+		return err
+	}
+
+	if _, err := wal.Seek(validSize, io.SeekStart); err != nil {
+		wal.Close()
+		return err
+	}
+
+	d.wal = wal
+	return nil
+}
+
+func (d *myDurableInMemoryRepository) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(d.dir, snapshotFileName))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	state := inMemoryState{}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	d.myInMemoryRepository.restore(state)
+	d.lastSeq = state.LastSeq
+	return nil
+}
+
+// replay applies every complete record newer than the snapshot and returns
+// the size of the valid prefix of the log
+func (d *myDurableInMemoryRepository) replay(wal *os.File) (int64, error) {
+	reader := bufio.NewReader(wal)
+	var validSize int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+
+		if errors.Is(err, io.EOF) {
+			// anything without a trailing newline is an incomplete write
+			return validSize, nil
+		}
+
+		if err != nil {
+			return validSize, err
+		}
+
+		record := walRecord{}
+
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Printf("Discarding corrupt write-ahead log tail at offset %d", validSize)
+			return validSize, nil
+		}
+
+		validSize += int64(len(line))
+
+		if record.Seq <= d.lastSeq {
+			// already part of the snapshot
+			continue
+		}
+
+		if err := d.apply(record); err != nil {
+			return validSize, err
+		}
+
+		d.lastSeq = record.Seq
+	}
+}
+
+func (d *myDurableInMemoryRepository) snapshotLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := d.Snapshot(); err != nil {
+			log.Printf("Couldn't write snapshot: %s", err)
+		}
+	}
+}
+
+// Snapshot writes the current state to disk and truncates the log. The
+// snapshot carries the sequence number it covers, so a crash between the two
+// steps never replays a record twice.
+func (d *myDurableInMemoryRepository) Snapshot() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := d.myInMemoryRepository.state()
+	state.LastSeq = d.lastSeq
+
+	data, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(d.dir, snapshotFileName+".tmp")
+
+	if err := writeFileSync(tmpPath, data); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(d.dir, snapshotFileName)); err != nil {
+		return err
+	}
+
+	if err := d.wal.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err = d.wal.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close closes the log, every mutation already returned is on disk. The
+// repository must not be used afterwards.
+func (d *myDurableInMemoryRepository) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.wal.Close()
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// state copies the repository contents out for a snapshot
+func (u *myInMemoryRepository) state() inMemoryState {
+	u.usersMu.RLock()
+	defer u.usersMu.RUnlock()
+	u.tweetsMu.RLock()
+	defer u.tweetsMu.RUnlock()
+	u.followsMu.RLock()
+	defer u.followsMu.RUnlock()
+	u.likesMu.RLock()
+	defer u.likesMu.RUnlock()
+	u.tokensMu.RLock()
+	defer u.tokensMu.RUnlock()
+	u.auditMu.RLock()
+	defer u.auditMu.RUnlock()
+
+	state := inMemoryState{
+		UserMap:           make(map[int]domain.User, len(u.userMap)),
+		CurrentNoOfUsers:  int(u.currentNoOfUsers.Load()),
+		TweetMap:          make(map[int]domain.Tweet, len(u.tweetMap)),
+		CurrentNoOfTweets: int(u.currentNoOfTweets.Load()),
+		Emaild2idMap:      make(map[string]int, len(u.emaild2idMap)),
+		RefreshTokens:     make(map[string]domain.RefreshToken, len(u.tokenRepo)),
+		AccessTokens:      make(map[string]domain.AccessToken, len(u.accessTokens)),
+		PasswordResets:    make(map[string]domain.PasswordReset, len(u.resets)),
+		AuditLog:          append([]domain.AuditEntry(nil), u.auditLog...),
+		Following:         make(map[int][]int, len(u.following)),
+		Likes:             make(map[int][]int, len(u.userLikes)),
+	}
+
+	for id, user := range u.userMap {
+		state.UserMap[id] = user
+	}
+
+	for id, tweet := range u.tweetMap {
+		state.TweetMap[id] = tweet
+	}
+
+	for email, id := range u.emaild2idMap {
+		state.Emaild2idMap[email] = id
+	}
+
+	for token, stored := range u.tokenRepo {
+		state.RefreshTokens[token] = stored
+	}
+
+	for id, stored := range u.accessTokens {
+		state.AccessTokens[id] = stored
+	}
+
+	for hash, reset := range u.resets {
+		state.PasswordResets[hash] = reset
+	}
+
+	for followerId, followeeIds := range u.following {
+		if len(followeeIds) > 0 {
+			state.Following[followerId] = append([]int(nil), followeeIds...)
+		}
+	}
+
+	for userId, tweetIds := range u.userLikes {
+		if len(tweetIds) > 0 {
+			state.Likes[userId] = append([]int(nil), tweetIds...)
+		}
+	}
+
+	state.Notifications, state.LastNotificationId = u.notifications.state()
+
+	return state
+}
+
+// restore replaces the repository contents with a snapshot
+func (u *myInMemoryRepository) restore(state inMemoryState) {
+	u.usersMu.Lock()
+	defer u.usersMu.Unlock()
+	u.tweetsMu.Lock()
+	defer u.tweetsMu.Unlock()
+	u.followsMu.Lock()
+	defer u.followsMu.Unlock()
+	u.likesMu.Lock()
+	defer u.likesMu.Unlock()
+	u.tokensMu.Lock()
+	defer u.tokensMu.Unlock()
+	u.auditMu.Lock()
+	defer u.auditMu.Unlock()
+
+	if state.UserMap != nil {
+		u.userMap = state.UserMap
+	}
+
+	// snapshots from before roles hold plain users
+	for id, user := range u.userMap {
+		if user.Role == "" {
+			user.Role = domain.RoleUser
+			u.userMap[id] = user
+		}
+	}
+
+	if state.TweetMap != nil {
+		u.tweetMap = state.TweetMap
+	}
+
+	// the ordered indexes are derived from tweetMap
+	u.tweetIndex = make([]int, 0, len(u.tweetMap))
+	u.authorIndex = make(map[int][]int)
+	u.retweets = make(map[int][]int)
+	u.replies = make(map[int][]int)
+	u.hashtagIndex = make(map[string][]int)
+	for id, tweet := range u.tweetMap {
+		// snapshots from before retweets and threads only hold originals
+		if tweet.Kind == "" {
+			tweet.Kind = domain.TweetKindOriginal
+		}
+		if tweet.ConversationId == 0 {
+			tweet.ConversationId = id
+		}
+		u.tweetMap[id] = tweet
+		u.tweetIndex = append(u.tweetIndex, id)
+	}
+	sort.Ints(u.tweetIndex)
+	for _, id := range u.tweetIndex {
+		tweet := u.tweetMap[id]
+		u.authorIndex[tweet.AuthorId] = append(u.authorIndex[tweet.AuthorId], id)
+		if tweet.Kind == domain.TweetKindRetweet {
+			u.retweets[tweet.ReferencedTweetId] = append(u.retweets[tweet.ReferencedTweetId], id)
+		}
+		if tweet.InReplyTo != 0 {
+			u.replies[tweet.InReplyTo] = append(u.replies[tweet.InReplyTo], id)
+		}
+		for _, tag := range tweet.Hashtags {
+			u.hashtagIndex[tag] = append(u.hashtagIndex[tag], id)
+		}
+	}
+
+	if state.Emaild2idMap != nil {
+		u.emaild2idMap = state.Emaild2idMap
+	}
+
+	// the handle index is derived from userMap
+	u.handle2idMap = make(map[string]int)
+	for id, user := range u.userMap {
+		if user.Handle != "" {
+			u.handle2idMap[handleKey(user.Handle)] = id
+		}
+	}
+
+	// the family indexes are derived from the tokens, snapshots from before
+	// families only hold plain tokens, which are dropped
+	u.tokenRepo = make(map[string]domain.RefreshToken, len(state.RefreshTokens))
+	u.tokenFamily = make(map[string][]string)
+	u.userFamilies = make(map[int][]string)
+	for _, stored := range state.RefreshTokens {
+		u.addRefreshToken(stored)
+	}
+
+	// as are the hash and user indexes of the access tokens
+	u.accessTokens = make(map[string]domain.AccessToken, len(state.AccessTokens))
+	u.accessHashes = make(map[string]string)
+	u.userAccess = make(map[int][]string)
+	for _, stored := range state.AccessTokens {
+		u.addAccessToken(stored)
+	}
+
+	// and the user index of the password resets
+	u.resets = make(map[string]domain.PasswordReset, len(state.PasswordResets))
+	u.userResets = make(map[int][]string)
+	for _, reset := range state.PasswordResets {
+		u.addPasswordReset(reset)
+	}
+
+	// followers are the reverse of following
+	u.following = make(map[int][]int, len(state.Following))
+	u.followers = make(map[int][]int)
+	for followerId, followeeIds := range state.Following {
+		u.following[followerId] = followeeIds
+		for _, followeeId := range followeeIds {
+			u.followers[followeeId] = insertSorted(u.followers[followeeId], followerId)
+		}
+	}
+
+	// likers per tweet are the reverse of likes per user
+	u.userLikes = make(map[int][]int, len(state.Likes))
+	u.tweetLikers = make(map[int][]int)
+	for userId, tweetIds := range state.Likes {
+		u.userLikes[userId] = tweetIds
+		for _, tweetId := range tweetIds {
+			u.tweetLikers[tweetId] = insertSorted(u.tweetLikers[tweetId], userId)
+		}
+	}
+
+	u.auditLog = state.AuditLog
+
+	u.notifications.restore(state.Notifications, state.LastNotificationId)
+
+	u.currentNoOfUsers.Store(int64(state.CurrentNoOfUsers))
+	u.currentNoOfTweets.Store(int64(state.CurrentNoOfTweets))
+}
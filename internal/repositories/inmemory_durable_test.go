@@ -0,0 +1,212 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestDurableInMemoryRepoRestart(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare mutates the repository and leaves it as it should be after a restart
+		prepare func(t *testing.T, repo *myDurableInMemoryRepository, dir string)
+	}{
+		{"LogOnly", func(t *testing.T, repo *myDurableInMemoryRepository, dir string) {
+			populateDurable(t, repo, 0)
+		}},
+		{"SnapshotAndLogTail", func(t *testing.T, repo *myDurableInMemoryRepository, dir string) {
+			populateDurable(t, repo, 0)
+
+			if err := repo.Snapshot(); err != nil {
+				t.Fatal(err)
+			}
+
+			populateDurable(t, repo, 1)
+		}},
+		{"TornLastRecord", func(t *testing.T, repo *myDurableInMemoryRepository, dir string) {
+			populateDurable(t, repo, 0)
+			repo.Close()
+
+			// a crash halfway through writing the next record
+			f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.WriteString(`{"seq":1000,"op":"save","user":{"Email":"torn@exa`); err != nil {
+				t.Fatal(err)
+			}
+
+			f.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			repo := openDurable(t, dir)
+
+			tt.prepare(t, repo, dir)
+
+			want := repo.state()
+			repo.Close()
+
+			restored := openDurable(t, dir)
+			assertSameState(t, want, restored.state())
+
+			if _, err := restored.GetUserId(ctx, "torn@example.com"); err == nil {
+				t.Fatal("the torn record was applied")
+			}
+
+			// new ids carry on after the restored ones, and the log keeps
+			// working after a torn tail was dropped
+			user, err := restored.Save(ctx, domain.User{Email: "after@example.com"})
+
+			if err != nil || user.ID != want.CurrentNoOfUsers+1 {
+				t.Fatalf("expected user %d after the restart, got %+v (%v)", want.CurrentNoOfUsers+1, user, err)
+			}
+
+			tweet, err := restored.SaveTweet(ctx, domain.Tweet{Body: "after", AuthorId: user.ID})
+
+			if err != nil || tweet.TweetId != want.CurrentNoOfTweets+1 {
+				t.Fatalf("expected tweet %d after the restart, got %+v (%v)", want.CurrentNoOfTweets+1, tweet, err)
+			}
+
+			want = restored.state()
+			restored.Close()
+
+			assertSameState(t, want, openDurable(t, dir).state())
+		})
+	}
+}
+
+func openDurable(t *testing.T, dir string) *myDurableInMemoryRepository {
+	t.Helper()
+
+	repo, err := ProvideDurableInMemoryRepo(dir, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	durable := repo.(*myDurableInMemoryRepository)
+	t.Cleanup(func() { durable.Close() })
+
+	return durable
+}
+
+// populateDurable makes a round of every kind of mutation, deletions and
+// rotations included, so replay has each of them to redo
+func populateDurable(t *testing.T, repo *myDurableInMemoryRepository, round int) {
+	t.Helper()
+
+	ctx := context.Background()
+	createdAt := time.Date(2030, 1, 1, round, 0, 0, 0, time.UTC)
+	users := make([]domain.User, 0, 3)
+
+	for i := 0; i < 3; i++ {
+		user, err := repo.Save(ctx, domain.User{
+			Email:          fmt.Sprintf("user-%d-%d@example.com", round, i),
+			HashedPassword: []byte("hash"),
+			CreatedAt:      createdAt,
+			UpdatedAt:      createdAt,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		users = append(users, user)
+	}
+
+	first, second, third := users[0], users[1], users[2]
+
+	mustDo(t,
+		repo.UpdateUser(ctx, first.ID, domain.User{Email: fmt.Sprintf("renamed-%d@example.com", round)}),
+		repo.UpdateProfile(ctx, second.ID, domain.User{Profile: domain.Profile{Handle: fmt.Sprintf("second_%d", round)}}),
+		repo.UpdateUserMembership(ctx, third.ID, true),
+		repo.UpdateUserRole(ctx, third.ID, domain.RoleModerator),
+		repo.Follow(ctx, first.ID, second.ID),
+		repo.Follow(ctx, first.ID, third.ID),
+		repo.Follow(ctx, second.ID, first.ID),
+		repo.Unfollow(ctx, first.ID, third.ID),
+	)
+
+	tweet, err := repo.SaveTweet(ctx, domain.Tweet{Body: "hello #go", AuthorId: first.ID, Hashtags: []string{"go"}, CreatedAt: createdAt})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gone, err := repo.SaveTweet(ctx, domain.Tweet{Body: "oops", AuthorId: second.ID, CreatedAt: createdAt})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.SaveTweet(ctx, domain.Tweet{Body: "reply", AuthorId: third.ID, InReplyTo: tweet.TweetId, ConversationId: tweet.TweetId, CreatedAt: createdAt})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	family := fmt.Sprintf("family-%d", round)
+	token := domain.RefreshToken{Token: family + "-a", UserId: first.ID, FamilyId: family, CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}
+	next := token
+	next.Token = family + "-b"
+
+	mustDo(t,
+		repo.LikeTweet(ctx, second.ID, tweet.TweetId),
+		repo.LikeTweet(ctx, third.ID, tweet.TweetId),
+		repo.LikeTweet(ctx, first.ID, gone.TweetId),
+		repo.UnlikeTweet(ctx, third.ID, tweet.TweetId),
+		repo.DeleteTweet(ctx, gone),
+		repo.SaveRefreshToken(ctx, token),
+		repo.RotateRefreshToken(ctx, token.Token, next),
+		repo.SaveRefreshToken(ctx, domain.RefreshToken{Token: family + "-revoked", UserId: second.ID, FamilyId: family + "-revoked"}),
+		repo.RevokeTokenFamily(ctx, family+"-revoked"),
+	)
+
+	for _, notification := range []domain.Notification{
+		{UserId: first.ID, Kind: domain.NotificationLike, ActorId: second.ID, TweetId: tweet.TweetId, CreatedAt: createdAt},
+		{UserId: first.ID, Kind: domain.NotificationFollow, ActorId: second.ID, CreatedAt: createdAt},
+		{UserId: second.ID, Kind: domain.NotificationFollow, ActorId: first.ID, CreatedAt: createdAt},
+	} {
+		if _, err := repo.SaveNotification(ctx, notification); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustDo(t, repo.MarkNotificationsRead(ctx, second.ID, nil))
+}
+
+func mustDo(t *testing.T, errs ...error) {
+	t.Helper()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("mutation %d: %s", i, err)
+		}
+	}
+}
+
+// assertSameState compares users, the email index, tweets, follows, likes,
+// tokens, notifications and the id counters of two repositories
+func assertSameState(t *testing.T, want inMemoryState, got inMemoryState) {
+	t.Helper()
+
+	want.LastSeq, got.LastSeq = 0, 0
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("state changed across the restart\nwant %+v\ngot  %+v", want, got)
+	}
+}
@@ -0,0 +1,63 @@
+package adapters
+
+import "sort"
+
+// Sorted id slices back the ordered indexes of the in-memory repository, so
+// listings can binary search to a cursor instead of scanning a whole map.
+
+// insertSorted adds id to the ascending slice ids, keeping it sorted
+func insertSorted(ids []int, id int) []int {
+	i := sort.SearchInts(ids, id)
+
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+// containsSorted reports whether id is in the ascending slice ids
+func containsSorted(ids []int, id int) bool {
+	i := sort.SearchInts(ids, id)
+	return i < len(ids) && ids[i] == id
+}
+
+// removeSorted drops id from the ascending slice ids if present
+func removeSorted(ids []int, id int) []int {
+	i := sort.SearchInts(ids, id)
+
+	if i == len(ids) || ids[i] != id {
+		return ids
+	}
+
+	return append(ids[:i], ids[i+1:]...)
+}
+
+// pageIds returns up to limit ids from the ascending slice ids, walking in
+// the requested direction and starting right after afterId (0 for the start)
+func pageIds(ids []int, descending bool, afterId int, limit int) []int {
+	page := make([]int, 0, min(limit, len(ids)))
+
+	if !descending {
+		// first id greater than afterId
+		for i := sort.SearchInts(ids, afterId+1); i < len(ids) && len(page) < limit; i++ {
+			page = append(page, ids[i])
+		}
+		return page
+	}
+
+	start := len(ids) - 1
+	if afterId > 0 {
+		// last id smaller than afterId
+		start = sort.SearchInts(ids, afterId) - 1
+	}
+
+	for i := start; i >= 0 && len(page) < limit; i-- {
+		page = append(page, ids[i])
+	}
+
+	return page
+}
@@ -0,0 +1,183 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+const (
+	stressWorkers    = 16
+	stressIterations = 200
+)
+
+// Run with -race, these tests hammer every method of the repository in parallel
+
+func TestInMemoryRepoConcurrentAccess(t *testing.T) {
+	stressRepository(t, ProvideInMemoryRepo())
+}
+
+func TestDurableInMemoryRepoConcurrentAccess(t *testing.T) {
+	repo, err := ProvideDurableInMemoryRepo(t.TempDir(), 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	durable := repo.(*myDurableInMemoryRepository)
+
+	// compact while the workers are writing
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			if err := durable.Snapshot(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	stressRepository(t, repo)
+	<-done
+}
+
+func stressRepository(t *testing.T, repo ports.IRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	userIds := make(chan int, stressWorkers*stressIterations)
+	tweetIds := make(chan int, stressWorkers*stressIterations)
+
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < stressIterations; i++ {
+				email := fmt.Sprintf("user-%d-%d@example.com", w, i)
+
+				user, err := repo.Save(ctx, domain.User{Email: email, HashedPassword: []byte("x")})
+				if err != nil {
+					t.Errorf("save %s: %s", email, err)
+					return
+				}
+				userIds <- user.ID
+
+				if _, err := repo.GetUserId(ctx, email); err != nil {
+					t.Errorf("lookup %s: %s", email, err)
+				}
+				if _, err := repo.GetUserById(ctx, user.ID); err != nil {
+					t.Errorf("get user %d: %s", user.ID, err)
+				}
+				handle := fmt.Sprintf("u%d_%d", w, i)
+				if err := repo.UpdateProfile(ctx, user.ID, domain.User{Profile: domain.Profile{Handle: handle}}); err != nil {
+					t.Errorf("update profile %d: %s", user.ID, err)
+				}
+				repo.GetUserIdByHandle(ctx, handle)
+				repo.UpdateUserMembership(ctx, user.ID, i%2 == 0)
+				repo.UpdateUserRole(ctx, user.ID, domain.RoleModerator)
+				repo.UpdateUserSuspension(ctx, user.ID, i%2 == 0)
+				repo.SaveAuditEntry(ctx, domain.AuditEntry{ActorId: user.ID, Action: domain.AuditSuspendUser, TargetUserId: user.ID})
+				repo.ListAuditEntries(ctx, 0, 20)
+				repo.UpdateUser(ctx, user.ID, domain.User{Email: "renamed-" + email, HashedPassword: []byte("y")})
+
+				tweet, err := repo.SaveTweet(ctx, domain.Tweet{Body: "hello #go", AuthorId: user.ID, Hashtags: []string{"go"}})
+				if err != nil {
+					t.Errorf("save tweet: %s", err)
+					return
+				}
+				tweetIds <- tweet.TweetId
+
+				repo.GetTweetById(ctx, tweet.TweetId)
+				repo.LikeTweet(ctx, user.ID, tweet.TweetId)
+				repo.LikeTweet(ctx, user.ID, tweet.TweetId-1)
+				repo.ListTweetLikers(ctx, tweet.TweetId, 0, 20)
+				repo.ListUserLikes(ctx, user.ID, 0, 20)
+				repo.LikeStats(ctx, user.ID, []int{tweet.TweetId, tweet.TweetId - 1})
+				repo.SaveTweet(ctx, domain.Tweet{AuthorId: user.ID, Kind: domain.TweetKindRetweet, ReferencedTweetId: tweet.TweetId - 1})
+				repo.RetweetStats(ctx, user.ID, []int{tweet.TweetId, tweet.TweetId - 1})
+				repo.SaveTweet(ctx, domain.Tweet{Body: "reply", AuthorId: user.ID, InReplyTo: tweet.TweetId})
+				repo.ListReplies(ctx, tweet.TweetId, 0, 20)
+				repo.CountReplies(ctx, []int{tweet.TweetId})
+				repo.ListTweetsByHashtag(ctx, "go", 0, 20)
+				repo.HashtagUses(ctx, time.Time{})
+				if i%4 == 0 {
+					repo.UnlikeTweet(ctx, user.ID, tweet.TweetId)
+				}
+				repo.ListTweets(ctx, domain.TweetFilter{Descending: i%2 == 0}, 0, 20)
+				repo.ListTweets(ctx, domain.TweetFilter{AuthorId: user.ID}, tweet.TweetId-1, 20)
+				if i%3 == 0 {
+					repo.DeleteTweet(ctx, tweet)
+				}
+
+				if i > 0 {
+					repo.Follow(ctx, user.ID, user.ID-1)
+					repo.ListFollowers(ctx, user.ID-1, 0, 20)
+					repo.ListFollowing(ctx, user.ID, 0, 20)
+					repo.CountFollows(ctx, user.ID)
+					if i%2 == 0 {
+						repo.Unfollow(ctx, user.ID, user.ID-1)
+					}
+				}
+
+				token := fmt.Sprintf("token-%d-%d", w, i)
+				family := fmt.Sprintf("family-%d", w)
+				if err := repo.SaveRefreshToken(ctx, domain.RefreshToken{Token: token, UserId: user.ID, FamilyId: family}); err != nil {
+					t.Errorf("save token %s: %s", token, err)
+				}
+				repo.GetRefreshToken(ctx, token)
+				repo.ListRefreshTokens(ctx, user.ID)
+				repo.RotateRefreshToken(ctx, token, domain.RefreshToken{Token: token + "-next", UserId: user.ID, FamilyId: family})
+				if i%10 == 0 {
+					repo.RevokeTokenFamily(ctx, family)
+				}
+
+				accessId := fmt.Sprintf("access-%d-%d", w, i)
+				if err := repo.SaveAccessToken(ctx, domain.AccessToken{Id: accessId, UserId: user.ID, Hash: accessId + "-hash"}); err != nil {
+					t.Errorf("save access token %s: %s", accessId, err)
+				}
+				repo.GetAccessTokenByHash(ctx, accessId+"-hash")
+				repo.ListAccessTokens(ctx, user.ID)
+				if i%2 == 0 {
+					repo.DeleteAccessToken(ctx, user.ID, accessId)
+				}
+
+				resetHash := fmt.Sprintf("reset-%d-%d", w, i)
+				if err := repo.SavePasswordReset(ctx, domain.PasswordReset{Hash: resetHash, UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+					t.Errorf("save password reset %s: %s", resetHash, err)
+				}
+				repo.UsePasswordReset(ctx, resetHash, time.Now())
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(userIds)
+	close(tweetIds)
+
+	assertUnique(t, "user", userIds)
+	assertUnique(t, "tweet", tweetIds)
+}
+
+func assertUnique(t *testing.T, kind string, ids <-chan int) {
+	t.Helper()
+
+	seen := make(map[int]bool)
+
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("%s id %d handed out twice", kind, id)
+		}
+		seen[id] = true
+	}
+
+	if len(seen) != stressWorkers*stressIterations {
+		t.Errorf("expected %d %s ids, got %d", stressWorkers*stressIterations, kind, len(seen))
+	}
+}
@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// outboxFileExt ends the name of every message in an outbox
+const outboxFileExt = ".eml"
+
+var errHeaderInjection = errors.New("email headers can't contain line breaks")
+
+// Outbox mailer for local development and tests. Instead of delivering
+// email it writes every message to its own file in dir, headers first, so
+// they can be read there. Names sort in the order the messages were sent.
+func ProvideOutboxMailer(dir string) (ports.IMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &myOutboxMailer{dir: dir}, nil
+}
+
+// myOutboxMailer implements ports.IMailer
+type myOutboxMailer struct {
+	dir string
+	// seq tells apart messages sent within the same nanosecond
+	seq atomic.Int64
+}
+
+func (m *myOutboxMailer) Send(ctx context.Context, email domain.Email) error {
+	if strings.ContainsAny(email.To+email.Subject, "\r\n") {
+		return errHeaderInjection
+	}
+
+	message := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", email.To, email.Subject, email.Body)
+	name := fmt.Sprintf("%s-%06d%s", time.Now().UTC().Format("20060102T150405.000000000Z"), m.seq.Add(1), outboxFileExt)
+
+	// written aside and renamed, so a reader never sees half a message
+	tmpPath := filepath.Join(m.dir, "."+name+".tmp")
+
+	if err := writeFileSync(tmpPath, []byte(message)); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(m.dir, name))
+}
@@ -0,0 +1,58 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestOutboxMailer(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer, err := ProvideOutboxMailer(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subject := range []string{"first", "second", "third"} {
+		if err := mailer.Send(ctx, domain.Email{To: "a@b.c", Subject: subject, Body: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := mailer.Send(ctx, domain.Email{To: "a@b.c\r\nBcc: d@e.f", Subject: "sneaky"}); err == nil {
+		t.Fatal("expected a recipient with a line break to be rejected")
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 messages in the outbox, got %d", len(entries))
+	}
+
+	// names sort in the order the messages were sent
+	data, err := os.ReadFile(filepath.Join(dir, entries[1].Name()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "To: a@b.c\nSubject: second\n\nhello\n"; string(data) != want {
+		t.Fatalf("expected %q, got %q", want, data)
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), outboxFileExt) {
+			t.Fatalf("unexpected file %s in the outbox", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package adapters
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// maxNotificationsPerUser bounds how many notifications are kept per user,
+// older ones are dropped
+const maxNotificationsPerUser = 1000
+
+// In memory notification store
+func ProvideInMemoryNotificationStore() ports.INotificationStore {
+	return newInMemoryNotificationStore()
+}
+
+func newInMemoryNotificationStore() *myInMemoryNotificationStore {
+	return &myInMemoryNotificationStore{
+		notifications: make(map[int][]domain.Notification),
+		ids:           make(map[notificationKey]int),
+	}
+}
+
+// myInMemoryNotificationStore implements ports.INotificationStore
+type myInMemoryNotificationStore struct {
+	mu     sync.RWMutex
+	lastId int
+	// notifications per user in ascending id order
+	notifications map[int][]domain.Notification
+	// ids of the kept notifications by what they are about
+	ids map[notificationKey]int
+}
+
+// notificationKey is what makes a notification a repeat of another
+type notificationKey struct {
+	userId  int
+	kind    domain.NotificationKind
+	actorId int
+	tweetId int
+}
+
+func keyOf(notification domain.Notification) notificationKey {
+	return notificationKey{notification.UserId, notification.Kind, notification.ActorId, notification.TweetId}
+}
+
+func (s *myInMemoryNotificationStore) Add(ctx context.Context, notification domain.Notification) (domain.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNotifications := s.notifications[notification.UserId]
+
+	if id, ok := s.ids[keyOf(notification)]; ok {
+		i := sort.Search(len(userNotifications), func(i int) bool { return userNotifications[i].Id >= id })
+		return userNotifications[i], nil
+	}
+
+	s.lastId++
+	notification.Id = s.lastId
+	s.ids[keyOf(notification)] = notification.Id
+
+	userNotifications = append(userNotifications, notification)
+
+	if len(userNotifications) > maxNotificationsPerUser {
+		dropped := len(userNotifications) - maxNotificationsPerUser
+		for _, old := range userNotifications[:dropped] {
+			delete(s.ids, keyOf(old))
+		}
+		userNotifications = append([]domain.Notification(nil), userNotifications[dropped:]...)
+	}
+
+	s.notifications[notification.UserId] = userNotifications
+
+	return notification, nil
+}
+
+func (s *myInMemoryNotificationStore) List(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	userNotifications := s.notifications[userId]
+	start := len(userNotifications) - 1
+
+	if beforeId > 0 {
+		// last notification older than beforeId
+		start = sort.Search(len(userNotifications), func(i int) bool { return userNotifications[i].Id >= beforeId }) - 1
+	}
+
+	page := make([]domain.Notification, 0, min(limit, start+1))
+
+	for i := start; i >= 0 && len(page) < limit; i-- {
+		if unreadOnly && userNotifications[i].Read {
+			continue
+		}
+		page = append(page, userNotifications[i])
+	}
+
+	return page, nil
+}
+
+func (s *myInMemoryNotificationStore) MarkRead(ctx context.Context, userId int, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNotifications := s.notifications[userId]
+
+	if len(ids) == 0 {
+		for i := range userNotifications {
+			userNotifications[i].Read = true
+		}
+		return nil
+	}
+
+	for _, id := range ids {
+		i := sort.Search(len(userNotifications), func(i int) bool { return userNotifications[i].Id >= id })
+
+		// ids of other users' notifications are ignored
+		if i < len(userNotifications) && userNotifications[i].Id == id {
+			userNotifications[i].Read = true
+		}
+	}
+
+	return nil
+}
+
+func (s *myInMemoryNotificationStore) CountUnread(ctx context.Context, userId int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	unread := 0
+
+	for _, notification := range s.notifications[userId] {
+		if !notification.Read {
+			unread++
+		}
+	}
+
+	return unread, nil
+}
+
+// state copies the notifications out for a snapshot, per user in ascending id order
+func (s *myInMemoryNotificationStore) state() (map[int][]domain.Notification, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notifications := make(map[int][]domain.Notification, len(s.notifications))
+
+	for userId, userNotifications := range s.notifications {
+		if len(userNotifications) > 0 {
+			notifications[userId] = append([]domain.Notification(nil), userNotifications...)
+		}
+	}
+
+	return notifications, s.lastId
+}
+
+// restore replaces the notifications with a snapshot
+func (s *myInMemoryNotificationStore) restore(notifications map[int][]domain.Notification, lastId int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId = lastId
+	s.notifications = make(map[int][]domain.Notification, len(notifications))
+
+	// the repeat index is derived from the notifications
+	s.ids = make(map[notificationKey]int)
+	for userId, userNotifications := range notifications {
+		s.notifications[userId] = userNotifications
+		for _, notification := range userNotifications {
+			s.ids[keyOf(notification)] = notification.Id
+		}
+	}
+}
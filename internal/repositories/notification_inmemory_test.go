@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestInMemoryNotificationStore(t *testing.T) {
+	ctx := context.Background()
+	store := ProvideInMemoryNotificationStore()
+
+	for i := 0; i < 5; i++ {
+		if _, err := store.Add(ctx, domain.Notification{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other, _ := store.Add(ctx, domain.Notification{UserId: 2, Kind: domain.NotificationFollow, ActorId: 1})
+
+	page, _ := store.List(ctx, 1, false, 0, 2)
+
+	if len(page) != 2 || page[0].Id != 5 || page[1].Id != 4 {
+		t.Fatalf("expected notifications 5 and 4 first, got %+v", page)
+	}
+
+	page, _ = store.List(ctx, 1, false, 4, 10)
+
+	if len(page) != 3 || page[0].Id != 3 {
+		t.Fatalf("expected notifications 3 down to 1 before 4, got %+v", page)
+	}
+
+	// another user's notification can't be marked through this user
+	if err := store.MarkRead(ctx, 1, []int{2, 5, other.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if unread, _ := store.CountUnread(ctx, 1); unread != 3 {
+		t.Fatalf("expected 3 unread notifications, got %d", unread)
+	}
+
+	if unread, _ := store.CountUnread(ctx, 2); unread != 1 {
+		t.Fatalf("expected the other user's notification to stay unread, got %d unread", unread)
+	}
+
+	page, _ = store.List(ctx, 1, true, 0, 10)
+
+	if len(page) != 3 || page[0].Id != 4 || page[1].Id != 3 || page[2].Id != 1 {
+		t.Fatalf("expected unread notifications 4, 3 and 1, got %+v", page)
+	}
+
+	if err := store.MarkRead(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if unread, _ := store.CountUnread(ctx, 1); unread != 0 {
+		t.Fatalf("expected everything read, got %d unread", unread)
+	}
+}
+
+func TestInMemoryNotificationStoreRepeats(t *testing.T) {
+	ctx := context.Background()
+	store := ProvideInMemoryNotificationStore()
+
+	like := domain.Notification{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: 7}
+	first, _ := store.Add(ctx, like)
+
+	if err := store.MarkRead(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// liking, unliking and liking again doesn't notify again
+	for i := 0; i < 5; i++ {
+		if again, _ := store.Add(ctx, like); again.Id != first.Id || !again.Read {
+			t.Fatalf("expected the read notification %d back, got %+v", first.Id, again)
+		}
+	}
+
+	// anything that differs is news
+	for _, other := range []domain.Notification{
+		{UserId: 1, Kind: domain.NotificationLike, ActorId: 3, TweetId: 7},
+		{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: 8},
+		{UserId: 1, Kind: domain.NotificationMention, ActorId: 2, TweetId: 7},
+		{UserId: 4, Kind: domain.NotificationLike, ActorId: 2, TweetId: 7},
+	} {
+		if added, _ := store.Add(ctx, other); added.Id == first.Id {
+			t.Fatalf("%+v was taken for a repeat", other)
+		}
+	}
+
+	if page, _ := store.List(ctx, 1, false, 0, 10); len(page) != 4 {
+		t.Fatalf("expected 4 notifications, got %+v", page)
+	}
+
+	// once the original falls off the end, the same event notifies afresh
+	for i := 0; i < maxNotificationsPerUser; i++ {
+		store.Add(ctx, domain.Notification{UserId: 1, Kind: domain.NotificationFollow, ActorId: 100 + i})
+	}
+
+	if again, _ := store.Add(ctx, like); again.Id == first.Id || again.Read {
+		t.Fatalf("expected a fresh notification, got %+v", again)
+	}
+}
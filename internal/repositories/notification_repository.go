@@ -0,0 +1,35 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// Notification store keeping notifications in the repository, they last as
+// long as whatever else the repository stores
+func ProvideRepositoryNotificationStore(repo ports.IRepository) ports.INotificationStore {
+	return &myRepositoryNotificationStore{repo: repo}
+}
+
+// myRepositoryNotificationStore implements ports.INotificationStore
+type myRepositoryNotificationStore struct {
+	repo ports.IRepository
+}
+
+func (s *myRepositoryNotificationStore) Add(ctx context.Context, notification domain.Notification) (domain.Notification, error) {
+	return s.repo.SaveNotification(ctx, notification)
+}
+
+func (s *myRepositoryNotificationStore) List(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error) {
+	return s.repo.ListNotifications(ctx, userId, unreadOnly, beforeId, limit)
+}
+
+func (s *myRepositoryNotificationStore) MarkRead(ctx context.Context, userId int, ids []int) error {
+	return s.repo.MarkNotificationsRead(ctx, userId, ids)
+}
+
+func (s *myRepositoryNotificationStore) CountUnread(ctx context.Context, userId int) (int, error) {
+	return s.repo.CountUnreadNotifications(ctx, userId)
+}
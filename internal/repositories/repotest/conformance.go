@@ -0,0 +1,1379 @@
+// Package repotest holds the behavioral contract every ports.IRepository
+// implementation must satisfy. Adapters plug their constructor into Run from
+// their own tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// Factory returns a fresh, empty repository for a single test
+type Factory func(t *testing.T) ports.IRepository
+
+// Run executes the whole conformance suite against repositories built by newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo ports.IRepository)
+	}{
+		{"SaveAssignsIds", testSaveAssignsIds},
+		{"SaveRejectsDuplicateEmail", testSaveRejectsDuplicateEmail},
+		{"GetUnknownUser", testGetUnknownUser},
+		{"UpdateUserReindexesEmail", testUpdateUserReindexesEmail},
+		{"UpdateUserKeepsOtherFields", testUpdateUserKeepsOtherFields},
+		{"UpdateUnknownUser", testUpdateUnknownUser},
+		{"UpdateUserMembership", testUpdateUserMembership},
+		{"RolesAndSuspension", testRolesAndSuspension},
+		{"Profiles", testProfiles},
+		{"TweetCrud", testTweetCrud},
+		{"ListTweetsByAuthor", testListTweetsByAuthor},
+		{"ListTweetsPages", testListTweetsPages},
+		{"ListTweetsTimeWindow", testListTweetsTimeWindow},
+		{"Timestamps", testTimestamps},
+		{"FollowGraph", testFollowGraph},
+		{"Likes", testLikes},
+		{"Retweets", testRetweets},
+		{"Replies", testReplies},
+		{"Hashtags", testHashtags},
+		{"Tokens", testTokens},
+		{"ListRefreshTokens", testListRefreshTokens},
+		{"GetLatestRefreshToken", testGetLatestRefreshToken},
+		{"AccessTokens", testAccessTokens},
+		{"PasswordResets", testPasswordResets},
+		{"AuditLog", testAuditLog},
+		{"Notifications", testNotifications},
+		{"NotificationRepeats", testNotificationRepeats},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustSaveUser(t *testing.T, repo ports.IRepository, email string) domain.User {
+	t.Helper()
+
+	user, err := repo.Save(context.Background(), domain.User{Email: email, HashedPassword: []byte("hash")})
+
+	if err != nil {
+		t.Fatalf("save %s: %s", email, err)
+	}
+
+	return user
+}
+
+func mustSaveTweet(t *testing.T, repo ports.IRepository, body string, authorId int) domain.Tweet {
+	t.Helper()
+
+	tweet, err := repo.SaveTweet(context.Background(), domain.Tweet{Body: body, AuthorId: authorId})
+
+	if err != nil {
+		t.Fatalf("save tweet: %s", err)
+	}
+
+	return tweet
+}
+
+func assertErrorKind(t *testing.T, err error, kind error) {
+	t.Helper()
+
+	if !errors.Is(err, kind) {
+		t.Fatalf("expected error of kind %q, got %v", kind, err)
+	}
+}
+
+func testSaveAssignsIds(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+
+	first := mustSaveUser(t, repo, "first@example.com")
+	second := mustSaveUser(t, repo, "second@example.com")
+
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Fatalf("expected increasing positive ids, got %d then %d", first.ID, second.ID)
+	}
+
+	stored, err := repo.GetUserById(ctx, second.ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.ID != second.ID || stored.Email != "second@example.com" || string(stored.HashedPassword) != "hash" {
+		t.Fatalf("stored user doesn't match saved one: %+v", stored)
+	}
+
+	id, err := repo.GetUserId(ctx, "first@example.com")
+
+	if err != nil || id != first.ID {
+		t.Fatalf("expected id %d for first@example.com, got %d (%v)", first.ID, id, err)
+	}
+}
+
+func testSaveRejectsDuplicateEmail(t *testing.T, repo ports.IRepository) {
+	original := mustSaveUser(t, repo, "taken@example.com")
+
+	_, err := repo.Save(context.Background(), domain.User{Email: "taken@example.com", HashedPassword: []byte("other")})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	stored, _ := repo.GetUserById(context.Background(), original.ID)
+
+	if string(stored.HashedPassword) != "hash" {
+		t.Fatal("rejected save overwrote the existing user")
+	}
+}
+
+func testGetUnknownUser(t *testing.T, repo ports.IRepository) {
+	_, err := repo.GetUserById(context.Background(), 42)
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	_, err = repo.GetUserId(context.Background(), "nobody@example.com")
+	assertErrorKind(t, err, domain.ErrNotFound)
+}
+
+func testUpdateUserReindexesEmail(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "old@example.com")
+
+	err := repo.UpdateUser(ctx, user.ID, domain.User{Email: "new@example.com", HashedPassword: []byte("new-hash")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetUserId(ctx, "old@example.com"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("old email still resolves: %v", err)
+	}
+
+	id, err := repo.GetUserId(ctx, "new@example.com")
+
+	if err != nil || id != user.ID {
+		t.Fatalf("new email should resolve to %d, got %d (%v)", user.ID, id, err)
+	}
+
+	stored, _ := repo.GetUserById(ctx, user.ID)
+
+	if stored.Email != "new@example.com" || string(stored.HashedPassword) != "new-hash" {
+		t.Fatalf("update not stored: %+v", stored)
+	}
+}
+
+func testUpdateUserKeepsOtherFields(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "keep@example.com")
+	other := mustSaveUser(t, repo, "other@example.com")
+
+	if err := repo.UpdateUserMembership(ctx, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// an empty password hash keeps the stored one
+	if err := repo.UpdateUser(ctx, user.ID, domain.User{Email: "kept@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := repo.GetUserById(ctx, user.ID)
+
+	if stored.Email != "kept@example.com" || string(stored.HashedPassword) != "hash" || !stored.IsChirpyRed {
+		t.Fatalf("update touched fields it wasn't given: %+v", stored)
+	}
+
+	err := repo.UpdateUser(ctx, user.ID, domain.User{Email: "other@example.com"})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	if id, err := repo.GetUserId(ctx, "other@example.com"); err != nil || id != other.ID {
+		t.Fatalf("rejected update remapped the other user's email: %d (%v)", id, err)
+	}
+
+	if id, err := repo.GetUserId(ctx, "kept@example.com"); err != nil || id != user.ID {
+		t.Fatalf("rejected update dropped the user's own email: %d (%v)", id, err)
+	}
+}
+
+func testUpdateUnknownUser(t *testing.T, repo ports.IRepository) {
+	err := repo.UpdateUser(context.Background(), 42, domain.User{Email: "ghost@example.com"})
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	err = repo.UpdateUserMembership(context.Background(), 42, true)
+	assertErrorKind(t, err, domain.ErrNotFound)
+}
+
+func testUpdateUserMembership(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "member@example.com")
+
+	if user.IsChirpyRed {
+		t.Fatal("new users shouldn't be members")
+	}
+
+	for _, isMember := range []bool{true, false} {
+		if err := repo.UpdateUserMembership(ctx, user.ID, isMember); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, _ := repo.GetUserById(ctx, user.ID)
+
+		if stored.IsChirpyRed != isMember {
+			t.Fatalf("expected membership %v, got %v", isMember, stored.IsChirpyRed)
+		}
+
+		if stored.Email != "member@example.com" {
+			t.Fatal("membership update touched other fields")
+		}
+	}
+}
+
+func testRolesAndSuspension(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "staff@example.com")
+
+	if user.Role != domain.RoleUser || user.Suspended {
+		t.Fatalf("new users should be active plain users: %+v", user)
+	}
+
+	admin, err := repo.Save(ctx, domain.User{Email: "admin@example.com", HashedPassword: []byte("x"), Role: domain.RoleAdmin})
+
+	if err != nil || admin.Role != domain.RoleAdmin {
+		t.Fatalf("role not kept on save: %+v (%v)", admin, err)
+	}
+
+	if err := repo.UpdateUserRole(ctx, user.ID, domain.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateUserSuspension(ctx, user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := repo.GetUserById(ctx, user.ID)
+
+	if stored.Role != domain.RoleModerator || !stored.Suspended || stored.Email != "staff@example.com" {
+		t.Fatalf("expected a suspended moderator, got %+v", stored)
+	}
+
+	// the account update leaves both alone
+	if err := repo.UpdateUser(ctx, user.ID, domain.User{Email: "renamed@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := repo.GetUserById(ctx, user.ID); stored.Role != domain.RoleModerator || !stored.Suspended {
+		t.Fatalf("account update touched the role or suspension: %+v", stored)
+	}
+
+	if err := repo.UpdateUserSuspension(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := repo.GetUserById(ctx, user.ID); stored.Suspended {
+		t.Fatal("user still suspended")
+	}
+
+	assertErrorKind(t, repo.UpdateUserRole(ctx, 42, domain.RoleAdmin), domain.ErrNotFound)
+	assertErrorKind(t, repo.UpdateUserSuspension(ctx, 42, true), domain.ErrNotFound)
+}
+
+func testProfiles(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	profile := domain.Profile{Handle: "Alice", DisplayName: "Alice A.", Bio: "hi", Location: "Earth"}
+
+	alice, err := repo.Save(ctx, domain.User{Email: "alice@example.com", HashedPassword: []byte("hash"), Profile: profile})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := repo.GetUserById(ctx, alice.ID)
+
+	if stored.Profile != profile {
+		t.Fatalf("expected profile %+v, got %+v", profile, stored.Profile)
+	}
+
+	for _, handle := range []string{"Alice", "alice", "ALICE"} {
+		if id, err := repo.GetUserIdByHandle(ctx, handle); err != nil || id != alice.ID {
+			t.Fatalf("handle %s should resolve to %d, got %d (%v)", handle, alice.ID, id, err)
+		}
+	}
+
+	_, err = repo.Save(ctx, domain.User{Email: "other@example.com", HashedPassword: []byte("hash"), Profile: domain.Profile{Handle: "aLiCe"}})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	// users without a handle don't collide with each other
+	first := mustSaveUser(t, repo, "first@example.com")
+	mustSaveUser(t, repo, "second@example.com")
+
+	if _, err := repo.GetUserIdByHandle(ctx, ""); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("the empty handle shouldn't resolve: %v", err)
+	}
+
+	err = repo.UpdateProfile(ctx, first.ID, domain.User{Profile: domain.Profile{Handle: "ALICE"}})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	if err := repo.UpdateProfile(ctx, first.ID, domain.User{Profile: domain.Profile{Handle: "bob", Bio: "bio"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := repo.GetUserIdByHandle(ctx, "BOB"); err != nil || id != first.ID {
+		t.Fatalf("handle bob should resolve to %d, got %d (%v)", first.ID, id, err)
+	}
+
+	// changing the casing of your own handle isn't a conflict, renaming frees the old one
+	if err := repo.UpdateProfile(ctx, alice.ID, domain.User{Profile: domain.Profile{Handle: "ALICE"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateProfile(ctx, alice.ID, domain.User{Profile: domain.Profile{Handle: "carol"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetUserIdByHandle(ctx, "alice"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("old handle still resolves: %v", err)
+	}
+
+	// account updates leave the profile alone
+	if err := repo.UpdateUser(ctx, first.ID, domain.User{Email: "first@example.com", HashedPassword: []byte("new-hash")}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ = repo.GetUserById(ctx, first.ID)
+
+	if stored.Handle != "bob" || stored.Bio != "bio" {
+		t.Fatalf("account update touched the profile: %+v", stored.Profile)
+	}
+
+	// clearing the handle frees it
+	if err := repo.UpdateProfile(ctx, first.ID, domain.User{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetUserIdByHandle(ctx, "bob"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("cleared handle still resolves: %v", err)
+	}
+
+	err = repo.UpdateProfile(ctx, 42, domain.User{Profile: domain.Profile{Handle: "ghost"}})
+	assertErrorKind(t, err, domain.ErrNotFound)
+}
+
+func testTweetCrud(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	author := mustSaveUser(t, repo, "author@example.com")
+
+	first := mustSaveTweet(t, repo, "first", author.ID)
+	second := mustSaveTweet(t, repo, "second", author.ID)
+
+	if first.TweetId <= 0 || second.TweetId <= first.TweetId {
+		t.Fatalf("expected increasing positive tweet ids, got %d then %d", first.TweetId, second.TweetId)
+	}
+
+	stored, err := repo.GetTweetById(ctx, first.TweetId)
+
+	if err != nil || stored.Body != "first" || stored.AuthorId != author.ID {
+		t.Fatalf("unexpected tweet %+v (%v)", stored, err)
+	}
+
+	if err := repo.DeleteTweet(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.GetTweetById(ctx, first.TweetId)
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	err = repo.DeleteTweet(ctx, first)
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	all, err := repo.ListTweets(ctx, domain.TweetFilter{}, 0, 10)
+
+	if err != nil || len(all) != 1 || all[0].TweetId != second.TweetId {
+		t.Fatalf("expected only tweet %d to remain, got %+v (%v)", second.TweetId, all, err)
+	}
+}
+
+func testListTweetsByAuthor(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	alice := mustSaveUser(t, repo, "alice@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+
+	mustSaveTweet(t, repo, "alice 1", alice.ID)
+	mustSaveTweet(t, repo, "bob 1", bob.ID)
+	mustSaveTweet(t, repo, "alice 2", alice.ID)
+
+	tweets, err := repo.ListTweets(ctx, domain.TweetFilter{AuthorId: alice.ID}, 0, 10)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tweets) != 2 {
+		t.Fatalf("expected 2 tweets by alice, got %d", len(tweets))
+	}
+
+	for _, tweet := range tweets {
+		if tweet.AuthorId != alice.ID {
+			t.Fatalf("tweet %d by %d leaked into alice's tweets", tweet.TweetId, tweet.AuthorId)
+		}
+	}
+
+	tweets, err = repo.ListTweets(ctx, domain.TweetFilter{AuthorId: 42}, 0, 10)
+
+	if err != nil || len(tweets) != 0 {
+		t.Fatalf("expected no tweets for unknown author, got %+v (%v)", tweets, err)
+	}
+}
+
+func testListTweetsPages(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	author := mustSaveUser(t, repo, "author@example.com")
+
+	ids := make([]int, 5)
+	for i := range ids {
+		ids[i] = mustSaveTweet(t, repo, "tweet", author.ID).TweetId
+	}
+
+	repo.DeleteTweet(ctx, domain.Tweet{TweetId: ids[2]})
+
+	tests := []struct {
+		name       string
+		descending bool
+		afterId    int
+		limit      int
+		want       []int
+	}{
+		{"first ascending page", false, 0, 2, []int{ids[0], ids[1]}},
+		{"ascending page skips deleted", false, ids[1], 2, []int{ids[3], ids[4]}},
+		{"last ascending page", false, ids[3], 2, []int{ids[4]}},
+		{"past the end", false, ids[4], 2, []int{}},
+		{"first descending page", true, 0, 2, []int{ids[4], ids[3]}},
+		{"descending page skips deleted", true, ids[3], 2, []int{ids[1], ids[0]}},
+		{"cursor on a deleted tweet", true, ids[2], 5, []int{ids[1], ids[0]}},
+	}
+
+	for _, tt := range tests {
+		tweets, err := repo.ListTweets(ctx, domain.TweetFilter{Descending: tt.descending}, tt.afterId, tt.limit)
+
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		got := make([]int, 0, len(tweets))
+		for _, tweet := range tweets {
+			got = append(got, tweet.TweetId)
+		}
+
+		if !equalIds(got, tt.want) {
+			t.Errorf("%s: expected ids %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func equalIds(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func testListTweetsTimeWindow(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	author := mustSaveUser(t, repo, "author@example.com")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ids := make([]int, 5)
+	for i := range ids {
+		createdAt := start.Add(time.Duration(i) * time.Hour)
+		tweet, err := repo.SaveTweet(ctx, domain.Tweet{Body: "tweet", AuthorId: author.ID, CreatedAt: createdAt, UpdatedAt: createdAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = tweet.TweetId
+	}
+
+	window := domain.TweetFilter{Since: start.Add(time.Hour), Until: start.Add(4 * time.Hour)}
+
+	tests := []struct {
+		name       string
+		descending bool
+		afterId    int
+		limit      int
+		want       []int
+	}{
+		{"ascending", false, 0, 10, []int{ids[1], ids[2], ids[3]}},
+		{"ascending with cursor", false, ids[1], 1, []int{ids[2]}},
+		{"descending", true, 0, 10, []int{ids[3], ids[2], ids[1]}},
+		{"descending with cursor", true, ids[2], 10, []int{ids[1]}},
+	}
+
+	for _, tt := range tests {
+		filter := window
+		filter.Descending = tt.descending
+
+		tweets, err := repo.ListTweets(ctx, filter, tt.afterId, tt.limit)
+
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		got := make([]int, 0, len(tweets))
+		for _, tweet := range tweets {
+			got = append(got, tweet.TweetId)
+		}
+
+		if !equalIds(got, tt.want) {
+			t.Errorf("%s: expected ids %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func testTimestamps(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+
+	user, err := repo.Save(ctx, domain.User{Email: "time@example.com", HashedPassword: []byte("hash"), CreatedAt: createdAt, UpdatedAt: createdAt})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateUser(ctx, user.ID, domain.User{Email: "time@example.com", HashedPassword: []byte("hash"), UpdatedAt: updatedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := repo.GetUserById(ctx, user.ID)
+
+	if !stored.CreatedAt.Equal(createdAt) || !stored.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("expected user timestamps %s/%s, got %s/%s", createdAt, updatedAt, stored.CreatedAt, stored.UpdatedAt)
+	}
+
+	tweet, _ := repo.SaveTweet(ctx, domain.Tweet{Body: "tweet", AuthorId: user.ID, CreatedAt: createdAt, UpdatedAt: createdAt})
+	storedTweet, _ := repo.GetTweetById(ctx, tweet.TweetId)
+
+	if !storedTweet.CreatedAt.Equal(createdAt) || !storedTweet.UpdatedAt.Equal(createdAt) {
+		t.Fatalf("expected tweet timestamps %s, got %s/%s", createdAt, storedTweet.CreatedAt, storedTweet.UpdatedAt)
+	}
+}
+
+func testFollowGraph(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	alice := mustSaveUser(t, repo, "alice@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+	carol := mustSaveUser(t, repo, "carol@example.com")
+
+	for _, followerId := range []int{carol.ID, bob.ID} {
+		if err := repo.Follow(ctx, followerId, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Follow(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorKind(t, repo.Follow(ctx, bob.ID, alice.ID), domain.ErrConflict)
+
+	followers, err := repo.ListFollowers(ctx, alice.ID, 0, 10)
+
+	if err != nil || !equalIds(followers, []int{bob.ID, carol.ID}) {
+		t.Fatalf("expected followers %v, got %v (%v)", []int{bob.ID, carol.ID}, followers, err)
+	}
+
+	followers, _ = repo.ListFollowers(ctx, alice.ID, bob.ID, 10)
+
+	if !equalIds(followers, []int{carol.ID}) {
+		t.Fatalf("expected followers after %d to be %v, got %v", bob.ID, []int{carol.ID}, followers)
+	}
+
+	following, _ := repo.ListFollowing(ctx, alice.ID, 0, 10)
+
+	if !equalIds(following, []int{bob.ID}) {
+		t.Fatalf("expected alice to follow %v, got %v", []int{bob.ID}, following)
+	}
+
+	stats, err := repo.CountFollows(ctx, alice.ID)
+
+	if err != nil || stats.Followers != 2 || stats.Following != 1 {
+		t.Fatalf("unexpected counts for alice %+v (%v)", stats, err)
+	}
+
+	if err := repo.Unfollow(ctx, bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorKind(t, repo.Unfollow(ctx, bob.ID, alice.ID), domain.ErrNotFound)
+
+	followers, _ = repo.ListFollowers(ctx, alice.ID, 0, 10)
+
+	if !equalIds(followers, []int{carol.ID}) {
+		t.Fatalf("expected followers %v after unfollow, got %v", []int{carol.ID}, followers)
+	}
+
+	stats, _ = repo.CountFollows(ctx, bob.ID)
+
+	if stats.Followers != 1 || stats.Following != 0 {
+		t.Fatalf("unexpected counts for bob %+v", stats)
+	}
+}
+
+func testLikes(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	alice := mustSaveUser(t, repo, "alice@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+	first := mustSaveTweet(t, repo, "first", alice.ID)
+	second := mustSaveTweet(t, repo, "second", alice.ID)
+
+	for _, like := range [][2]int{{bob.ID, first.TweetId}, {alice.ID, first.TweetId}, {bob.ID, second.TweetId}} {
+		if err := repo.LikeTweet(ctx, like[0], like[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertErrorKind(t, repo.LikeTweet(ctx, bob.ID, first.TweetId), domain.ErrConflict)
+	assertErrorKind(t, repo.LikeTweet(ctx, bob.ID, 9999), domain.ErrNotFound)
+	assertErrorKind(t, repo.UnlikeTweet(ctx, alice.ID, second.TweetId), domain.ErrNotFound)
+	assertErrorKind(t, repo.UnlikeTweet(ctx, alice.ID, 9999), domain.ErrNotFound)
+
+	likers, err := repo.ListTweetLikers(ctx, first.TweetId, 0, 10)
+
+	if err != nil || !equalIds(likers, []int{alice.ID, bob.ID}) {
+		t.Fatalf("expected likers %v, got %v (%v)", []int{alice.ID, bob.ID}, likers, err)
+	}
+
+	likers, _ = repo.ListTweetLikers(ctx, first.TweetId, alice.ID, 10)
+
+	if !equalIds(likers, []int{bob.ID}) {
+		t.Fatalf("expected likers after %d to be %v, got %v", alice.ID, []int{bob.ID}, likers)
+	}
+
+	liked, err := repo.ListUserLikes(ctx, bob.ID, 0, 10)
+
+	if err != nil || !equalIds(liked, []int{second.TweetId, first.TweetId}) {
+		t.Fatalf("expected bob's likes newest first %v, got %v (%v)", []int{second.TweetId, first.TweetId}, liked, err)
+	}
+
+	liked, _ = repo.ListUserLikes(ctx, bob.ID, second.TweetId, 10)
+
+	if !equalIds(liked, []int{first.TweetId}) {
+		t.Fatalf("expected bob's likes before %d to be %v, got %v", second.TweetId, []int{first.TweetId}, liked)
+	}
+
+	stats, err := repo.LikeStats(ctx, alice.ID, []int{first.TweetId, second.TweetId, 9999})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int]domain.LikeStats{
+		first.TweetId:  {Count: 2, Liked: true},
+		second.TweetId: {Count: 1, Liked: false},
+		9999:           {},
+	}
+
+	for tweetId, want := range expected {
+		if got, ok := stats[tweetId]; !ok || got != want {
+			t.Errorf("expected stats %+v for tweet %d, got %+v", want, tweetId, got)
+		}
+	}
+
+	if err := repo.UnlikeTweet(ctx, alice.ID, first.TweetId); err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting a tweet takes its likes with it
+	if err := repo.DeleteTweet(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	liked, _ = repo.ListUserLikes(ctx, bob.ID, 0, 10)
+
+	if !equalIds(liked, []int{first.TweetId}) {
+		t.Fatalf("expected bob's likes %v after delete, got %v", []int{first.TweetId}, liked)
+	}
+
+	stats, _ = repo.LikeStats(ctx, bob.ID, []int{first.TweetId, second.TweetId})
+
+	if stats[first.TweetId] != (domain.LikeStats{Count: 1, Liked: true}) || stats[second.TweetId] != (domain.LikeStats{}) {
+		t.Fatalf("unexpected stats after unlike and delete %+v", stats)
+	}
+}
+
+func testRetweets(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	alice := mustSaveUser(t, repo, "alice@example.com")
+	bob := mustSaveUser(t, repo, "bob@example.com")
+	original := mustSaveTweet(t, repo, "original", alice.ID)
+
+	if original.Kind != domain.TweetKindOriginal {
+		t.Fatalf("expected a tweet without a kind to be saved as %q, got %q", domain.TweetKindOriginal, original.Kind)
+	}
+
+	repost := func(authorId int, kind domain.TweetKind, body string) (domain.Tweet, error) {
+		return repo.SaveTweet(ctx, domain.Tweet{Body: body, AuthorId: authorId, Kind: kind, ReferencedTweetId: original.TweetId})
+	}
+
+	retweet, err := repost(bob.ID, domain.TweetKindRetweet, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repost(bob.ID, domain.TweetKindRetweet, "")
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	if _, err := repost(alice.ID, domain.TweetKindRetweet, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// quoting is not limited to once per user
+	quote, err := repost(bob.ID, domain.TweetKindQuote, "look at this")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repost(bob.ID, domain.TweetKindQuote, "again"); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := repo.GetTweetById(ctx, retweet.TweetId)
+
+	if err != nil || fetched.Kind != domain.TweetKindRetweet || fetched.ReferencedTweetId != original.TweetId {
+		t.Fatalf("expected a retweet of %d, got %+v (%v)", original.TweetId, fetched, err)
+	}
+
+	stats, err := repo.RetweetStats(ctx, bob.ID, []int{original.TweetId, quote.TweetId})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats[original.TweetId] != (domain.RetweetStats{Count: 2, Retweeted: true}) {
+		t.Fatalf("unexpected stats for the original %+v", stats[original.TweetId])
+	}
+
+	if _, ok := stats[quote.TweetId]; !ok || stats[quote.TweetId].Count != 0 {
+		t.Fatalf("unexpected stats for the quote %+v", stats[quote.TweetId])
+	}
+
+	if err := repo.LikeTweet(ctx, alice.ID, retweet.TweetId); err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting the original takes its retweets with it but keeps the quotes
+	if err := repo.DeleteTweet(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.GetTweetById(ctx, retweet.TweetId)
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	fetched, err = repo.GetTweetById(ctx, quote.TweetId)
+
+	if err != nil || fetched.ReferencedTweetId != original.TweetId {
+		t.Fatalf("expected the quote to survive, got %+v (%v)", fetched, err)
+	}
+
+	tweets, _ := repo.ListTweets(ctx, domain.TweetFilter{}, 0, 10)
+
+	if len(tweets) != 2 {
+		t.Fatalf("expected only the two quotes to be left, got %+v", tweets)
+	}
+
+	liked, _ := repo.ListUserLikes(ctx, alice.ID, 0, 10)
+
+	if len(liked) != 0 {
+		t.Fatalf("expected likes of the deleted retweet to be gone, got %v", liked)
+	}
+
+	stats, _ = repo.RetweetStats(ctx, bob.ID, []int{original.TweetId})
+
+	if stats[original.TweetId] != (domain.RetweetStats{}) {
+		t.Fatalf("expected no retweets of a deleted tweet, got %+v", stats[original.TweetId])
+	}
+}
+
+func testReplies(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	alice := mustSaveUser(t, repo, "alice@example.com")
+	root := mustSaveTweet(t, repo, "root", alice.ID)
+
+	if root.ConversationId != root.TweetId {
+		t.Fatalf("expected a new tweet to start conversation %d, got %d", root.TweetId, root.ConversationId)
+	}
+
+	reply := func(parent domain.Tweet, body string) domain.Tweet {
+		t.Helper()
+
+		saved, err := repo.SaveTweet(ctx, domain.Tweet{
+			Body: body, AuthorId: alice.ID, InReplyTo: parent.TweetId, ConversationId: parent.ConversationId,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return saved
+	}
+
+	first := reply(root, "first")
+	second := reply(root, "second")
+	nested := reply(first, "nested")
+	mustSaveTweet(t, repo, "unrelated", alice.ID)
+
+	if nested.ConversationId != root.TweetId {
+		t.Fatalf("expected the nested reply to be part of conversation %d, got %d", root.TweetId, nested.ConversationId)
+	}
+
+	replies, err := repo.ListReplies(ctx, root.TweetId, 0, 10)
+
+	if err != nil || len(replies) != 2 || replies[0].TweetId != first.TweetId || replies[1].TweetId != second.TweetId {
+		t.Fatalf("expected replies %d and %d, got %+v (%v)", first.TweetId, second.TweetId, replies, err)
+	}
+
+	replies, _ = repo.ListReplies(ctx, root.TweetId, first.TweetId, 10)
+
+	if len(replies) != 1 || replies[0].TweetId != second.TweetId || replies[0].InReplyTo != root.TweetId {
+		t.Fatalf("expected reply %d after %d, got %+v", second.TweetId, first.TweetId, replies)
+	}
+
+	counts, err := repo.CountReplies(ctx, []int{root.TweetId, first.TweetId, nested.TweetId})
+
+	if err != nil || counts[root.TweetId] != 2 || counts[first.TweetId] != 1 || counts[nested.TweetId] != 0 {
+		t.Fatalf("unexpected reply counts %v (%v)", counts, err)
+	}
+
+	// deleting a reply drops it from its parent, its own replies stay
+	if err := repo.DeleteTweet(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	replies, _ = repo.ListReplies(ctx, root.TweetId, 0, 10)
+
+	if len(replies) != 1 || replies[0].TweetId != second.TweetId {
+		t.Fatalf("expected only reply %d after delete, got %+v", second.TweetId, replies)
+	}
+
+	replies, _ = repo.ListReplies(ctx, first.TweetId, 0, 10)
+
+	if len(replies) != 1 || replies[0].TweetId != nested.TweetId {
+		t.Fatalf("expected reply %d to outlive its parent, got %+v", nested.TweetId, replies)
+	}
+}
+
+func testHashtags(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	author := mustSaveUser(t, repo, "author@example.com")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tagged := make([]domain.Tweet, 0)
+
+	for i, tags := range [][]string{{"go"}, {"go", "sqlite"}, nil, {"sqlite"}, {"go"}} {
+		createdAt := start.Add(time.Duration(i) * time.Hour)
+		tweet, err := repo.SaveTweet(ctx, domain.Tweet{Body: "tweet", AuthorId: author.ID, Hashtags: tags, CreatedAt: createdAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagged = append(tagged, tweet)
+	}
+
+	tweets, err := repo.ListTweetsByHashtag(ctx, "go", 0, 10)
+
+	if err != nil || !equalIds(tweetIds(tweets), []int{tagged[4].TweetId, tagged[1].TweetId, tagged[0].TweetId}) {
+		t.Fatalf("expected #go tweets newest first, got %+v (%v)", tweets, err)
+	}
+
+	if len(tweets[1].Hashtags) != 2 || tweets[1].Hashtags[0] != "go" || tweets[1].Hashtags[1] != "sqlite" {
+		t.Fatalf("expected hashtags to be stored with the tweet, got %v", tweets[1].Hashtags)
+	}
+
+	tweets, _ = repo.ListTweetsByHashtag(ctx, "go", tagged[4].TweetId, 1)
+
+	if !equalIds(tweetIds(tweets), []int{tagged[1].TweetId}) {
+		t.Fatalf("expected #go tweet %d before %d, got %+v", tagged[1].TweetId, tagged[4].TweetId, tweets)
+	}
+
+	uses, err := repo.HashtagUses(ctx, start.Add(time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+
+	for _, use := range uses {
+		if use.CreatedAt.Before(start.Add(time.Hour)) {
+			t.Fatalf("expected uses since %s only, got %+v", start.Add(time.Hour), use)
+		}
+		counts[use.Tag]++
+	}
+
+	if len(counts) != 2 || counts["go"] != 2 || counts["sqlite"] != 2 {
+		t.Fatalf("unexpected hashtag uses %v", counts)
+	}
+
+	if err := repo.DeleteTweet(ctx, tagged[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	tweets, _ = repo.ListTweetsByHashtag(ctx, "sqlite", 0, 10)
+
+	if !equalIds(tweetIds(tweets), []int{tagged[3].TweetId}) {
+		t.Fatalf("expected the deleted tweet to leave the #sqlite index, got %+v", tweets)
+	}
+
+	uses, _ = repo.HashtagUses(ctx, start)
+
+	if len(uses) != 3 {
+		t.Fatalf("expected 3 uses left after the delete, got %+v", uses)
+	}
+}
+
+func tweetIds(tweets []domain.Tweet) []int {
+	ids := make([]int, 0, len(tweets))
+
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.TweetId)
+	}
+
+	return ids
+}
+
+func testTokens(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "tokens@example.com")
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := domain.RefreshToken{Token: "first", UserId: user.ID, FamilyId: "family", ExpiresAt: expiresAt}
+
+	if err := repo.SaveRefreshToken(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorKind(t, repo.SaveRefreshToken(ctx, first), domain.ErrConflict)
+
+	stored, err := repo.GetRefreshToken(ctx, "first")
+
+	if err != nil || stored != first {
+		t.Fatalf("expected %+v, got %+v (%v)", first, stored, err)
+	}
+
+	second := domain.RefreshToken{Token: "second", UserId: user.ID, FamilyId: "family", ExpiresAt: expiresAt}
+
+	if err := repo.RotateRefreshToken(ctx, "first", second); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := repo.GetRefreshToken(ctx, "first"); !stored.Rotated || stored.Revoked {
+		t.Fatalf("rotated token should only be marked rotated: %+v", stored)
+	}
+
+	if stored, _ := repo.GetRefreshToken(ctx, "second"); stored != second {
+		t.Fatalf("successor not stored: %+v", stored)
+	}
+
+	// a token only rotates once, a failed rotation stores nothing
+	err = repo.RotateRefreshToken(ctx, "first", domain.RefreshToken{Token: "third", FamilyId: "family"})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	if _, err := repo.GetRefreshToken(ctx, "third"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("failed rotation stored its successor: %v", err)
+	}
+
+	other := domain.RefreshToken{Token: "other", UserId: user.ID, FamilyId: "other-family"}
+
+	if err := repo.SaveRefreshToken(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RevokeTokenFamily(ctx, "family"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"first", "second"} {
+		if stored, _ := repo.GetRefreshToken(ctx, token); !stored.Revoked {
+			t.Fatalf("token %s of the revoked family isn't revoked", token)
+		}
+	}
+
+	if stored, _ := repo.GetRefreshToken(ctx, "other"); stored.Revoked {
+		t.Fatal("revoking a family revoked another one")
+	}
+
+	err = repo.RotateRefreshToken(ctx, "second", domain.RefreshToken{Token: "third", FamilyId: "family"})
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	_, err = repo.GetRefreshToken(ctx, "unknown")
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	err = repo.RotateRefreshToken(ctx, "unknown", domain.RefreshToken{Token: "fourth"})
+	assertErrorKind(t, err, domain.ErrNotFound)
+}
+
+func testListRefreshTokens(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "sessions@example.com")
+	other := mustSaveUser(t, repo, "other@example.com")
+	loggedInAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := domain.Client{UserAgent: "curl/8.0", IP: "192.0.2.1"}
+
+	for _, token := range []domain.RefreshToken{
+		{Token: "laptop-1", UserId: user.ID, FamilyId: "laptop", Client: client, LoggedInAt: loggedInAt, CreatedAt: loggedInAt},
+		{Token: "phone-1", UserId: user.ID, FamilyId: "phone"},
+		{Token: "revoked-1", UserId: user.ID, FamilyId: "revoked"},
+		{Token: "other-1", UserId: other.ID, FamilyId: "other"},
+	} {
+		if err := repo.SaveRefreshToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	laptop := domain.RefreshToken{Token: "laptop-2", UserId: user.ID, FamilyId: "laptop", Client: client, LoggedInAt: loggedInAt, CreatedAt: loggedInAt.Add(time.Hour)}
+
+	if err := repo.RotateRefreshToken(ctx, "laptop-1", laptop); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RevokeTokenFamily(ctx, "revoked"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := repo.ListRefreshTokens(ctx, user.ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := make(map[string]domain.RefreshToken)
+	for _, token := range tokens {
+		current[token.FamilyId] = token
+	}
+
+	if len(tokens) != 2 || current["laptop"] != laptop || current["phone"].Token != "phone-1" {
+		t.Fatalf("expected the latest laptop and phone tokens, got %+v", tokens)
+	}
+
+	if tokens, _ := repo.ListRefreshTokens(ctx, 42); len(tokens) != 0 {
+		t.Fatalf("unknown user has tokens: %+v", tokens)
+	}
+}
+
+func testGetLatestRefreshToken(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "session@example.com")
+	loggedInAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := repo.GetLatestRefreshToken(ctx, "unknown")
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	latest := domain.RefreshToken{Token: "session-0", UserId: user.ID, FamilyId: "session", LoggedInAt: loggedInAt, CreatedAt: loggedInAt, ExpiresAt: loggedInAt}
+
+	if err := repo.SaveRefreshToken(ctx, latest); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		next := latest
+		next.Token = fmt.Sprintf("session-%d", i)
+		next.CreatedAt = loggedInAt.Add(time.Duration(i) * time.Hour)
+
+		if err := repo.RotateRefreshToken(ctx, latest.Token, next); err != nil {
+			t.Fatal(err)
+		}
+
+		latest = next
+	}
+
+	// expired tokens are returned all the same, the caller decides
+	if got, err := repo.GetLatestRefreshToken(ctx, "session"); err != nil || got != latest {
+		t.Fatalf("expected %+v, got %+v (%v)", latest, got, err)
+	}
+
+	if err := repo.RevokeTokenFamily(ctx, "session"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.GetLatestRefreshToken(ctx, "session")
+	assertErrorKind(t, err, domain.ErrNotFound)
+}
+
+func testAccessTokens(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "bots@example.com")
+	other := mustSaveUser(t, repo, "other@example.com")
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	poster := domain.AccessToken{
+		Id:        "poster",
+		UserId:    user.ID,
+		Name:      "Poster bot",
+		Scopes:    []domain.Scope{domain.ScopeTweetsRead, domain.ScopeTweetsWrite},
+		Hash:      "poster-hash",
+		CreatedAt: createdAt.Add(time.Hour),
+	}
+	reader := domain.AccessToken{Id: "reader", UserId: user.ID, Name: "Reader", Scopes: []domain.Scope{domain.ScopeTweetsRead}, Hash: "reader-hash", CreatedAt: createdAt}
+
+	for _, token := range []domain.AccessToken{poster, reader, {Id: "others", UserId: other.ID, Name: "Other", Hash: "others-hash"}} {
+		if err := repo.SaveAccessToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertErrorKind(t, repo.SaveAccessToken(ctx, domain.AccessToken{Id: "poster", UserId: user.ID, Hash: "fresh-hash"}), domain.ErrConflict)
+	assertErrorKind(t, repo.SaveAccessToken(ctx, domain.AccessToken{Id: "fresh", UserId: user.ID, Hash: "poster-hash"}), domain.ErrConflict)
+
+	stored, err := repo.GetAccessTokenByHash(ctx, "poster-hash")
+
+	if err != nil || !reflect.DeepEqual(stored, poster) {
+		t.Fatalf("expected %+v, got %+v (%v)", poster, stored, err)
+	}
+
+	_, err = repo.GetAccessTokenByHash(ctx, "unknown-hash")
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	tokens, err := repo.ListAccessTokens(ctx, user.ID)
+
+	if err != nil || len(tokens) != 2 || tokens[0].Id != "reader" || !reflect.DeepEqual(tokens[1], poster) {
+		t.Fatalf("expected the reader then the poster, got %+v (%v)", tokens, err)
+	}
+
+	// only the owner deletes a token
+	assertErrorKind(t, repo.DeleteAccessToken(ctx, other.ID, "poster"), domain.ErrNotFound)
+
+	if err := repo.DeleteAccessToken(ctx, user.ID, "poster"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErrorKind(t, repo.DeleteAccessToken(ctx, user.ID, "poster"), domain.ErrNotFound)
+
+	if _, err := repo.GetAccessTokenByHash(ctx, "poster-hash"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("deleted token still authenticates: %v", err)
+	}
+
+	if tokens, _ := repo.ListAccessTokens(ctx, user.ID); len(tokens) != 1 || tokens[0].Id != "reader" {
+		t.Fatalf("expected only the reader left, got %+v", tokens)
+	}
+
+	if tokens, _ := repo.ListAccessTokens(ctx, 42); len(tokens) != 0 {
+		t.Fatalf("unknown user has tokens: %+v", tokens)
+	}
+}
+
+func testPasswordResets(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	user := mustSaveUser(t, repo, "forgetful@example.com")
+	other := mustSaveUser(t, repo, "other@example.com")
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := domain.PasswordReset{Hash: "first-hash", UserId: user.ID, CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}
+	second := domain.PasswordReset{Hash: "second-hash", UserId: user.ID, CreatedAt: createdAt.Add(time.Minute), ExpiresAt: createdAt.Add(time.Hour + time.Minute)}
+	others := domain.PasswordReset{Hash: "others-hash", UserId: other.ID, CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}
+	stale := domain.PasswordReset{Hash: "stale-hash", UserId: user.ID, CreatedAt: createdAt.Add(-time.Hour), ExpiresAt: createdAt}
+	now := createdAt.Add(time.Minute)
+
+	for _, reset := range []domain.PasswordReset{first, second, others, stale} {
+		if err := repo.SavePasswordReset(ctx, reset); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertErrorKind(t, repo.SavePasswordReset(ctx, domain.PasswordReset{Hash: "first-hash", UserId: other.ID}), domain.ErrConflict)
+
+	_, err := repo.UsePasswordReset(ctx, "unknown-hash", now)
+	assertErrorKind(t, err, domain.ErrNotFound)
+
+	// an expired reset is turned down without spending the live ones
+	_, err = repo.UsePasswordReset(ctx, "stale-hash", now)
+	assertErrorKind(t, err, domain.ErrTokenExpired)
+
+	used, err := repo.UsePasswordReset(ctx, "second-hash", now)
+
+	if err != nil || !reflect.DeepEqual(used, second) {
+		t.Fatalf("expected %+v, got %+v (%v)", second, used, err)
+	}
+
+	// a reset works once, and using one spends every other reset of the user
+	_, err = repo.UsePasswordReset(ctx, "second-hash", now)
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	_, err = repo.UsePasswordReset(ctx, "first-hash", now)
+	assertErrorKind(t, err, domain.ErrConflict)
+
+	if used, err := repo.UsePasswordReset(ctx, "others-hash", now); err != nil || used.UserId != other.ID {
+		t.Fatalf("another user's reset was spent: %+v (%v)", used, err)
+	}
+}
+
+func testAuditLog(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if entries, err := repo.ListAuditEntries(ctx, 0, 10); err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty log, got %+v (%v)", entries, err)
+	}
+
+	ids := make([]int, 0)
+
+	for i, action := range []domain.AuditAction{domain.AuditSuspendUser, domain.AuditDeleteTweet, domain.AuditGrantRole} {
+		entry, err := repo.SaveAuditEntry(ctx, domain.AuditEntry{
+			ActorId:      1,
+			Action:       action,
+			TargetUserId: 2,
+			TweetId:      i,
+			Detail:       "detail",
+			Reason:       "reason",
+			CreatedAt:    createdAt,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ids) > 0 && entry.Id <= ids[len(ids)-1] {
+			t.Fatalf("entry ids should increase, got %d after %d", entry.Id, ids[len(ids)-1])
+		}
+
+		ids = append(ids, entry.Id)
+	}
+
+	entries, err := repo.ListAuditEntries(ctx, 0, 2)
+
+	if err != nil || len(entries) != 2 || entries[0].Id != ids[2] || entries[1].Id != ids[1] {
+		t.Fatalf("expected the two newest entries, got %+v (%v)", entries, err)
+	}
+
+	expected := domain.AuditEntry{
+		Id:           ids[2],
+		ActorId:      1,
+		Action:       domain.AuditGrantRole,
+		TargetUserId: 2,
+		TweetId:      2,
+		Detail:       "detail",
+		Reason:       "reason",
+		CreatedAt:    createdAt,
+	}
+
+	if entries[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, entries[0])
+	}
+
+	if entries, _ := repo.ListAuditEntries(ctx, ids[1], 10); len(entries) != 1 || entries[0].Id != ids[0] {
+		t.Fatalf("expected the oldest entry, got %+v", entries)
+	}
+}
+
+func testNotifications(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		notification := domain.Notification{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: i + 1, CreatedAt: createdAt}
+
+		if _, err := repo.SaveNotification(ctx, notification); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other, err := repo.SaveNotification(ctx, domain.Notification{UserId: 2, Kind: domain.NotificationFollow, ActorId: 1, CreatedAt: createdAt})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, _ := repo.ListNotifications(ctx, 1, false, 0, 2)
+	want := domain.Notification{Id: 5, UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: 5, CreatedAt: createdAt}
+
+	if len(page) != 2 || page[0] != want || page[1].Id != 4 {
+		t.Fatalf("expected notifications 5 and 4 first, got %+v", page)
+	}
+
+	page, _ = repo.ListNotifications(ctx, 1, false, 4, 10)
+
+	if len(page) != 3 || page[0].Id != 3 {
+		t.Fatalf("expected notifications 3 down to 1 before 4, got %+v", page)
+	}
+
+	// another user's notification can't be marked through this user
+	if err := repo.MarkNotificationsRead(ctx, 1, []int{2, 5, other.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if unread, _ := repo.CountUnreadNotifications(ctx, 1); unread != 3 {
+		t.Fatalf("expected 3 unread notifications, got %d", unread)
+	}
+
+	if unread, _ := repo.CountUnreadNotifications(ctx, 2); unread != 1 {
+		t.Fatalf("expected the other user's notification to stay unread, got %d unread", unread)
+	}
+
+	page, _ = repo.ListNotifications(ctx, 1, true, 0, 10)
+
+	if len(page) != 3 || page[0].Id != 4 || page[1].Id != 3 || page[2].Id != 1 {
+		t.Fatalf("expected unread notifications 4, 3 and 1, got %+v", page)
+	}
+
+	if err := repo.MarkNotificationsRead(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if unread, _ := repo.CountUnreadNotifications(ctx, 1); unread != 0 {
+		t.Fatalf("expected everything read, got %d unread", unread)
+	}
+}
+
+func testNotificationRepeats(t *testing.T, repo ports.IRepository) {
+	ctx := context.Background()
+
+	like := domain.Notification{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: 7}
+	first, _ := repo.SaveNotification(ctx, like)
+
+	if err := repo.MarkNotificationsRead(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// liking, unliking and liking again doesn't notify again
+	for i := 0; i < 5; i++ {
+		if again, _ := repo.SaveNotification(ctx, like); again.Id != first.Id || !again.Read {
+			t.Fatalf("expected the read notification %d back, got %+v", first.Id, again)
+		}
+	}
+
+	// anything that differs is news
+	for _, other := range []domain.Notification{
+		{UserId: 1, Kind: domain.NotificationLike, ActorId: 3, TweetId: 7},
+		{UserId: 1, Kind: domain.NotificationLike, ActorId: 2, TweetId: 8},
+		{UserId: 1, Kind: domain.NotificationMention, ActorId: 2, TweetId: 7},
+		{UserId: 4, Kind: domain.NotificationLike, ActorId: 2, TweetId: 7},
+	} {
+		if added, _ := repo.SaveNotification(ctx, other); added.Id == first.Id {
+			t.Fatalf("%+v was taken for a repeat", other)
+		}
+	}
+
+	if page, _ := repo.ListNotifications(ctx, 1, false, 0, 10); len(page) != 4 {
+		t.Fatalf("expected 4 notifications, got %+v", page)
+	}
+
+	// adapters keep a user's newest 1000 notifications, once the original
+	// falls off the end the same event notifies afresh
+	for i := 0; i < 1000; i++ {
+		if _, err := repo.SaveNotification(ctx, domain.Notification{UserId: 1, Kind: domain.NotificationFollow, ActorId: 100 + i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if again, _ := repo.SaveNotification(ctx, like); again.Id == first.Id || again.Read {
+		t.Fatalf("expected a fresh notification, got %+v", again)
+	}
+}
@@ -0,0 +1,264 @@
+package adapters
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// In memory search index
+func ProvideInMemorySearchIndex() ports.ITweetSearchIndex {
+	return &myInMemorySearchIndex{
+		postings:   make(map[string]map[int][]int),
+		docs:       make(map[int]searchDoc),
+		authorDocs: make(map[int][]int),
+	}
+}
+
+// myInMemorySearchIndex implements ports.ITweetSearchIndex as an inverted
+// index from terms to the tweets they appear in
+type myInMemorySearchIndex struct {
+	mu sync.RWMutex
+	// postings holds the ascending positions of every term in every tweet it appears in
+	postings map[string]map[int][]int
+	docs     map[int]searchDoc
+	// ascending ids of the indexed tweets per author
+	authorDocs map[int][]int
+}
+
+// searchDoc is what the index remembers about a tweet
+type searchDoc struct {
+	authorId int
+	terms    []string
+	length   int
+}
+
+// tokenize splits text into case-folded words, anything but letters and
+// digits separates them
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (s *myInMemorySearchIndex) Index(ctx context.Context, tweet domain.Tweet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(tweet.TweetId)
+
+	tokens := tokenize(tweet.Body)
+
+	if len(tokens) == 0 {
+		// nothing to find it by, retweets for one
+		return nil
+	}
+
+	doc := searchDoc{authorId: tweet.AuthorId, length: len(tokens)}
+
+	for position, term := range tokens {
+		tweetPositions, ok := s.postings[term]
+
+		if !ok {
+			tweetPositions = make(map[int][]int)
+			s.postings[term] = tweetPositions
+		}
+
+		if _, seen := tweetPositions[tweet.TweetId]; !seen {
+			doc.terms = append(doc.terms, term)
+		}
+
+		tweetPositions[tweet.TweetId] = append(tweetPositions[tweet.TweetId], position)
+	}
+
+	s.docs[tweet.TweetId] = doc
+	s.authorDocs[tweet.AuthorId] = insertSorted(s.authorDocs[tweet.AuthorId], tweet.TweetId)
+
+	return nil
+}
+
+func (s *myInMemorySearchIndex) Remove(ctx context.Context, tweetId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(tweetId)
+	return nil
+}
+
+// remove drops a tweet from the index if it's in there. Callers hold s.mu.
+func (s *myInMemorySearchIndex) remove(tweetId int) {
+	doc, ok := s.docs[tweetId]
+
+	if !ok {
+		return
+	}
+
+	for _, term := range doc.terms {
+		delete(s.postings[term], tweetId)
+
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+
+	delete(s.docs, tweetId)
+	s.authorDocs[doc.authorId] = removeSorted(s.authorDocs[doc.authorId], tweetId)
+}
+
+func (s *myInMemorySearchIndex) Search(ctx context.Context, query domain.SearchQuery, afterKey int, limit int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := make([]string, 0)
+
+	for _, term := range query.Terms {
+		terms = append(terms, tokenize(term)...)
+	}
+
+	phrases := make([][]string, 0, len(query.Phrases))
+
+	for _, phrase := range query.Phrases {
+		if tokens := tokenize(phrase); len(tokens) > 0 {
+			phrases = append(phrases, tokens)
+			terms = append(terms, tokens...)
+		}
+	}
+
+	candidates, err := s.candidates(ctx, terms, query.AuthorId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]int, 0, len(candidates))
+
+	for _, tweetId := range candidates {
+		if query.AuthorId != 0 && s.docs[tweetId].authorId != query.AuthorId {
+			continue
+		}
+
+		if s.containsPhrases(tweetId, phrases) {
+			matches = append(matches, tweetId)
+		}
+	}
+
+	if query.Order == domain.SearchByRecency {
+		sort.Sort(sort.Reverse(sort.IntSlice(matches)))
+
+		start := 0
+		if afterKey > 0 {
+			// first id older than the last one on the previous page
+			start = sort.Search(len(matches), func(i int) bool { return matches[i] < afterKey })
+		}
+
+		return matches[start:min(start+limit, len(matches))], nil
+	}
+
+	scores := make(map[int]float64, len(matches))
+
+	for _, tweetId := range matches {
+		scores[tweetId] = s.score(tweetId, terms)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if scores[matches[i]] != scores[matches[j]] {
+			return scores[matches[i]] > scores[matches[j]]
+		}
+		return matches[i] > matches[j]
+	})
+
+	start := min(afterKey, len(matches))
+	return matches[start:min(start+limit, len(matches))], nil
+}
+
+// candidates returns the tweets containing every term, or all the tweets of
+// the author when there are no terms
+func (s *myInMemorySearchIndex) candidates(ctx context.Context, terms []string, authorId int) ([]int, error) {
+	if len(terms) == 0 {
+		return append([]int(nil), s.authorDocs[authorId]...), nil
+	}
+
+	// walk the rarest term and check the others against it
+	rarest := terms[0]
+
+	for _, term := range terms[1:] {
+		if len(s.postings[term]) < len(s.postings[rarest]) {
+			rarest = term
+		}
+	}
+
+	candidates := make([]int, 0)
+	checked := 0
+
+	for tweetId := range s.postings[rarest] {
+		if checked++; checked%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		containsAll := true
+
+		for _, term := range terms {
+			if _, ok := s.postings[term][tweetId]; !ok {
+				containsAll = false
+				break
+			}
+		}
+
+		if containsAll {
+			candidates = append(candidates, tweetId)
+		}
+	}
+
+	return candidates, nil
+}
+
+// containsPhrases reports whether every phrase appears in the tweet word for word
+func (s *myInMemorySearchIndex) containsPhrases(tweetId int, phrases [][]string) bool {
+	for _, phrase := range phrases {
+		found := false
+
+		for _, start := range s.postings[phrase[0]][tweetId] {
+			found = true
+
+			for offset, term := range phrase[1:] {
+				if !containsSorted(s.postings[term][tweetId], start+offset+1) {
+					found = false
+					break
+				}
+			}
+
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// score ranks a tweet by tf-idf over the query terms, normalized by the
+// length of the tweet so short tweets about a term beat long ones mentioning it
+func (s *myInMemorySearchIndex) score(tweetId int, terms []string) float64 {
+	score := 0.0
+
+	for _, term := range terms {
+		tweetPositions := s.postings[term]
+		tf := float64(len(tweetPositions[tweetId]))
+		idf := math.Log(1 + float64(len(s.docs))/float64(len(tweetPositions)))
+		score += tf * idf
+	}
+
+	return score / math.Sqrt(float64(s.docs[tweetId].length))
+}
@@ -0,0 +1,71 @@
+package adapters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+)
+
+func TestInMemorySearchIndex(t *testing.T) {
+	ctx := context.Background()
+	index := ProvideInMemorySearchIndex()
+
+	tweets := []domain.Tweet{
+		{TweetId: 1, AuthorId: 1, Body: "Learning Go today"},
+		{TweetId: 2, AuthorId: 2, Body: "go go GO, the go gopher says go"},
+		{TweetId: 3, AuthorId: 1, Body: "Today I go learning"},
+		{TweetId: 4, AuthorId: 2, Body: "nothing to see here"},
+		{TweetId: 5, AuthorId: 2, Kind: domain.TweetKindRetweet},
+	}
+
+	for _, tweet := range tweets {
+		if err := index.Index(ctx, tweet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    domain.SearchQuery
+		afterKey int
+		limit    int
+		want     []int
+	}{
+		{"terms by recency", domain.SearchQuery{Terms: []string{"go"}, Order: domain.SearchByRecency}, 0, 10, []int{3, 2, 1}},
+		{"recency cursor", domain.SearchQuery{Terms: []string{"go"}, Order: domain.SearchByRecency}, 3, 1, []int{2}},
+		{"case folding", domain.SearchQuery{Terms: []string{"LEARNING", "today"}, Order: domain.SearchByRecency}, 0, 10, []int{3, 1}},
+		{"phrase", domain.SearchQuery{Phrases: []string{"learning go"}, Order: domain.SearchByRecency}, 0, 10, []int{1}},
+		{"author filter", domain.SearchQuery{Terms: []string{"go"}, AuthorId: 2, Order: domain.SearchByRecency}, 0, 10, []int{2}},
+		{"author only", domain.SearchQuery{AuthorId: 1, Order: domain.SearchByRecency}, 0, 10, []int{3, 1}},
+		{"relevance", domain.SearchQuery{Terms: []string{"go"}, Order: domain.SearchByRelevance}, 0, 1, []int{2}},
+		{"relevance offset", domain.SearchQuery{Terms: []string{"go"}, Order: domain.SearchByRelevance}, 1, 10, []int{1, 3}},
+		{"no match", domain.SearchQuery{Terms: []string{"rust"}, Order: domain.SearchByRecency}, 0, 10, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := index.Search(ctx, tt.query, tt.afterKey, tt.limit)
+
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v (%v)", tt.want, got, err)
+			}
+		})
+	}
+
+	if err := index.Remove(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// reindexing replaces the old version
+	if err := index.Index(ctx, domain.Tweet{TweetId: 1, AuthorId: 1, Body: "rust now"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := index.Search(ctx, domain.SearchQuery{Terms: []string{"go"}, Order: domain.SearchByRecency}, 0, 10)
+
+	if !reflect.DeepEqual(got, []int{3}) {
+		t.Fatalf("expected only tweet 3 to be left, got %v", got)
+	}
+}
@@ -0,0 +1,1445 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations are applied in order, PRAGMA user_version records how
+// many of them a database has seen. Append new ones, never edit old ones.
+var sqliteMigrations = []string{
+	`
+	CREATE TABLE IF NOT EXISTS users (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		email           TEXT    NOT NULL UNIQUE,
+		hashed_password BLOB    NOT NULL,
+		is_chirpy_red   INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS tweets (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		body      TEXT    NOT NULL,
+		author_id INTEGER NOT NULL REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS tweets_by_author ON tweets(author_id, id);
+
+	CREATE TABLE IF NOT EXISTS follows (
+		follower_id INTEGER NOT NULL REFERENCES users(id),
+		followee_id INTEGER NOT NULL REFERENCES users(id),
+		PRIMARY KEY (follower_id, followee_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS follows_by_followee ON follows(followee_id, follower_id);
+
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		token   TEXT    PRIMARY KEY,
+		revoked INTEGER NOT NULL DEFAULT 0
+	);
+	`,
+
+	`
+	ALTER TABLE users ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tweets ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tweets ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+	`,
+
+	`
+	CREATE TABLE IF NOT EXISTS likes (
+		user_id  INTEGER NOT NULL REFERENCES users(id),
+		tweet_id INTEGER NOT NULL REFERENCES tweets(id) ON DELETE CASCADE,
+		PRIMARY KEY (user_id, tweet_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS likes_by_tweet ON likes(tweet_id, user_id);
+	`,
+
+	`
+	ALTER TABLE tweets ADD COLUMN kind TEXT NOT NULL DEFAULT 'original';
+	ALTER TABLE tweets ADD COLUMN referenced_tweet_id INTEGER NOT NULL DEFAULT 0;
+
+	CREATE UNIQUE INDEX IF NOT EXISTS retweets_by_reference ON tweets(referenced_tweet_id, author_id) WHERE kind = 'retweet';
+	`,
+
+	`
+	ALTER TABLE tweets ADD COLUMN in_reply_to INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tweets ADD COLUMN conversation_id INTEGER NOT NULL DEFAULT 0;
+
+	UPDATE tweets SET conversation_id = id;
+
+	CREATE INDEX IF NOT EXISTS tweets_by_parent ON tweets(in_reply_to, id);
+	`,
+
+	`
+	ALTER TABLE tweets ADD COLUMN hashtags TEXT NOT NULL DEFAULT '';
+
+	CREATE TABLE IF NOT EXISTS hashtags (
+		tag        TEXT    NOT NULL,
+		tweet_id   INTEGER NOT NULL REFERENCES tweets(id) ON DELETE CASCADE,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (tag, tweet_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS hashtags_by_time ON hashtags(created_at);
+	`,
+
+	`
+	ALTER TABLE users ADD COLUMN handle TEXT COLLATE NOCASE;
+	ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN location TEXT NOT NULL DEFAULT '';
+
+	CREATE UNIQUE INDEX IF NOT EXISTS users_by_handle ON users(handle);
+	`,
+
+	`
+	-- tokens stored before families can't be rotated, their users log in again
+	DELETE FROM refresh_tokens;
+
+	ALTER TABLE refresh_tokens ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE refresh_tokens ADD COLUMN family_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE refresh_tokens ADD COLUMN rotated INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE refresh_tokens ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE refresh_tokens ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS refresh_tokens_by_family ON refresh_tokens(family_id);
+	`,
+
+	`
+	ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+	ALTER TABLE refresh_tokens ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+	ALTER TABLE refresh_tokens ADD COLUMN logged_in_at INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS refresh_tokens_by_user ON refresh_tokens(user_id);
+	`,
+	// personal access tokens, scopes separated by spaces
+	`
+	CREATE TABLE IF NOT EXISTS access_tokens (
+		id         TEXT    PRIMARY KEY,
+		user_id    INTEGER NOT NULL,
+		name       TEXT    NOT NULL,
+		scopes     TEXT    NOT NULL,
+		hash       TEXT    NOT NULL UNIQUE,
+		created_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS access_tokens_by_user ON access_tokens(user_id);
+	`,
+	`
+	ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+	ALTER TABLE users ADD COLUMN suspended INTEGER NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id       INTEGER NOT NULL,
+		action         TEXT    NOT NULL,
+		target_user_id INTEGER NOT NULL,
+		tweet_id       INTEGER NOT NULL,
+		detail         TEXT    NOT NULL,
+		reason         TEXT    NOT NULL,
+		created_at     INTEGER NOT NULL
+	);
+	`,
+	`
+	CREATE TABLE IF NOT EXISTS password_resets (
+		hash       TEXT    PRIMARY KEY,
+		user_id    INTEGER NOT NULL,
+		used       INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS password_resets_by_user ON password_resets(user_id);
+	`,
+	// the live token of a session, looked up on every request, whatever
+	// number of tokens the session rotated through
+	`
+	CREATE INDEX IF NOT EXISTS refresh_tokens_live_by_family ON refresh_tokens(family_id) WHERE rotated = 0 AND revoked = 0;
+	`,
+	// a notification is unique to what it is about, repeats aren't stored
+	`
+	CREATE TABLE IF NOT EXISTS notifications (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL,
+		kind       TEXT    NOT NULL,
+		actor_id   INTEGER NOT NULL,
+		tweet_id   INTEGER NOT NULL,
+		read       INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		UNIQUE (user_id, kind, actor_id, tweet_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS notifications_by_user ON notifications(user_id, id);
+	`,
+}
+
+// SQLite implementation
+func ProvideSQLiteRepo(path string) (ports.IRepository, error) {
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite only supports a single writer, serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &mySQLiteRepository{db: db}, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	var version int
+
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("sqlite migration %d: %w", version+1, err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mySQLiteRepository implements ports.IRepository
+type mySQLiteRepository struct {
+	db *sql.DB
+}
+
+// tweetColumns is the column list scanTweets expects. The hashtags column
+// holds the tags of a tweet separated by spaces, the hashtags table indexes them.
+const tweetColumns = `id, body, author_id, kind, referenced_tweet_id, in_reply_to, conversation_id, hashtags, created_at, updated_at`
+
+func (s *mySQLiteRepository) SaveRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	return insertRefreshToken(ctx, s.db, token)
+}
+
+// execer is what *sql.DB and *sql.Tx have in common for writes
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// refreshTokenColumns is the column list scanRefreshToken expects
+const refreshTokenColumns = `token, user_id, family_id, rotated, revoked, user_agent, ip, logged_in_at, created_at, expires_at`
+
+func insertRefreshToken(ctx context.Context, db execer, token domain.RefreshToken) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.Token, token.UserId, token.FamilyId, token.Rotated, token.Revoked, token.UserAgent, token.IP,
+		toUnixNano(token.LoggedInAt), toUnixNano(token.CreatedAt), toUnixNano(token.ExpiresAt),
+	)
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintPrimaryKey) {
+		return domain.ErrTokenExists
+	}
+
+	return err
+}
+
+func (s *mySQLiteRepository) GetRefreshToken(ctx context.Context, token string) (domain.RefreshToken, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token = ?`, token)
+	stored, err := scanRefreshToken(row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return stored, domain.ErrTokenNotFound
+	}
+
+	return stored, err
+}
+
+func (s *mySQLiteRepository) ListRefreshTokens(ctx context.Context, userId int) ([]domain.RefreshToken, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE user_id = ? AND rotated = 0 AND revoked = 0`, userId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := make([]domain.RefreshToken, 0)
+
+	for rows.Next() {
+		stored, err := scanRefreshToken(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, stored)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (s *mySQLiteRepository) GetLatestRefreshToken(ctx context.Context, familyId string) (domain.RefreshToken, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE family_id = ? AND rotated = 0 AND revoked = 0`, familyId,
+	)
+	stored, err := scanRefreshToken(row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.RefreshToken{}, domain.ErrTokenNotFound
+	}
+
+	return stored, err
+}
+
+// scanRefreshToken reads one row selected with refreshTokenColumns
+func scanRefreshToken(row interface{ Scan(dest ...any) error }) (domain.RefreshToken, error) {
+	stored := domain.RefreshToken{}
+	var loggedInAt, createdAt, expiresAt int64
+
+	err := row.Scan(&stored.Token, &stored.UserId, &stored.FamilyId, &stored.Rotated, &stored.Revoked,
+		&stored.UserAgent, &stored.IP, &loggedInAt, &createdAt, &expiresAt)
+
+	stored.LoggedInAt = fromUnixNano(loggedInAt)
+	stored.CreatedAt = fromUnixNano(createdAt)
+	stored.ExpiresAt = fromUnixNano(expiresAt)
+
+	return stored, err
+}
+
+func (s *mySQLiteRepository) RotateRefreshToken(ctx context.Context, token string, next domain.RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET rotated = 1 WHERE token = ? AND rotated = 0 AND revoked = 0`, token,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE token = ?)`, token).Scan(&exists)
+
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return domain.ErrTokenNotFound
+		}
+
+		return domain.ErrTokenRotated
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *mySQLiteRepository) RevokeTokenFamily(ctx context.Context, familyId string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = 1 WHERE family_id = ?`, familyId)
+	return err
+}
+
+func (s *mySQLiteRepository) SaveAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_log (actor_id, action, target_user_id, tweet_id, detail, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.ActorId, entry.Action, entry.TargetUserId, entry.TweetId, entry.Detail, entry.Reason, toUnixNano(entry.CreatedAt),
+	)
+
+	if err != nil {
+		return entry, err
+	}
+
+	entryId, err := result.LastInsertId()
+
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Id = int(entryId)
+	return entry, nil
+}
+
+func (s *mySQLiteRepository) ListAuditEntries(ctx context.Context, beforeId int, limit int) ([]domain.AuditEntry, error) {
+	query := `SELECT id, actor_id, action, target_user_id, tweet_id, detail, reason, created_at FROM audit_log`
+	args := []any{}
+
+	if beforeId > 0 {
+		query += ` WHERE id < ?`
+		args = append(args, beforeId)
+	}
+
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+
+	for rows.Next() {
+		entry := domain.AuditEntry{}
+		var createdAt int64
+
+		err := rows.Scan(&entry.Id, &entry.ActorId, &entry.Action, &entry.TargetUserId, &entry.TweetId,
+			&entry.Detail, &entry.Reason, &createdAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entry.CreatedAt = fromUnixNano(createdAt)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// notificationColumns is the column list scanNotification expects
+const notificationColumns = `id, user_id, kind, actor_id, tweet_id, read, created_at`
+
+func (s *mySQLiteRepository) SaveNotification(ctx context.Context, notification domain.Notification) (domain.Notification, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return notification, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO notifications (user_id, kind, actor_id, tweet_id, read, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		notification.UserId, notification.Kind, notification.ActorId, notification.TweetId, notification.Read,
+		toUnixNano(notification.CreatedAt),
+	)
+
+	if err != nil {
+		return notification, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// a repeat, the user keeps the notification they already have
+		row := tx.QueryRowContext(ctx,
+			`SELECT `+notificationColumns+` FROM notifications WHERE user_id = ? AND kind = ? AND actor_id = ? AND tweet_id = ?`,
+			notification.UserId, notification.Kind, notification.ActorId, notification.TweetId,
+		)
+		return scanNotification(row)
+	}
+
+	notificationId, err := result.LastInsertId()
+
+	if err != nil {
+		return notification, err
+	}
+
+	// only the newest maxNotificationsPerUser are kept
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM notifications WHERE user_id = ? AND id <= (
+		   SELECT id FROM notifications WHERE user_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+		 )`,
+		notification.UserId, notification.UserId, maxNotificationsPerUser,
+	)
+
+	if err != nil {
+		return notification, err
+	}
+
+	notification.Id = int(notificationId)
+	return notification, tx.Commit()
+}
+
+func (s *mySQLiteRepository) ListNotifications(ctx context.Context, userId int, unreadOnly bool, beforeId int, limit int) ([]domain.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE user_id = ?`
+	args := []any{userId}
+
+	if unreadOnly {
+		query += ` AND read = 0`
+	}
+
+	if beforeId > 0 {
+		query += ` AND id < ?`
+		args = append(args, beforeId)
+	}
+
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	notifications := make([]domain.Notification, 0)
+
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (s *mySQLiteRepository) MarkNotificationsRead(ctx context.Context, userId int, ids []int) error {
+	query := `UPDATE notifications SET read = 1 WHERE user_id = ?`
+	args := []any{userId}
+
+	if len(ids) > 0 {
+		query += ` AND id IN (` + strings.Repeat(`?, `, len(ids)-1) + `?)`
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s *mySQLiteRepository) CountUnreadNotifications(ctx context.Context, userId int) (int, error) {
+	var unread int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read = 0`, userId).Scan(&unread)
+	return unread, err
+}
+
+// scanNotification reads one row selected with notificationColumns
+func scanNotification(row interface{ Scan(dest ...any) error }) (domain.Notification, error) {
+	notification := domain.Notification{}
+	var createdAt int64
+
+	err := row.Scan(&notification.Id, &notification.UserId, &notification.Kind, &notification.ActorId,
+		&notification.TweetId, &notification.Read, &createdAt)
+
+	notification.CreatedAt = fromUnixNano(createdAt)
+
+	return notification, err
+}
+
+func (s *mySQLiteRepository) SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO password_resets (hash, user_id, used, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		reset.Hash, reset.UserId, reset.Used, toUnixNano(reset.CreatedAt), toUnixNano(reset.ExpiresAt),
+	)
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintPrimaryKey) {
+		return domain.ErrTokenExists
+	}
+
+	return err
+}
+
+func (s *mySQLiteRepository) UsePasswordReset(ctx context.Context, hash string, now time.Time) (domain.PasswordReset, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return domain.PasswordReset{}, err
+	}
+
+	defer tx.Rollback()
+
+	reset := domain.PasswordReset{Hash: hash}
+	var createdAt, expiresAt int64
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT user_id, used, created_at, expires_at FROM password_resets WHERE hash = ?`, hash,
+	).Scan(&reset.UserId, &reset.Used, &createdAt, &expiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PasswordReset{}, domain.ErrTokenNotFound
+	}
+
+	if err != nil {
+		return domain.PasswordReset{}, err
+	}
+
+	reset.CreatedAt = fromUnixNano(createdAt)
+	reset.ExpiresAt = fromUnixNano(expiresAt)
+
+	if !reset.Used && !now.Before(reset.ExpiresAt) {
+		return domain.PasswordReset{}, domain.ErrTokenExpired
+	}
+
+	// the guard on used makes the first of two concurrent uses the only winner
+	result, err := tx.ExecContext(ctx, `UPDATE password_resets SET used = 1 WHERE hash = ? AND used = 0`, hash)
+
+	if err != nil {
+		return domain.PasswordReset{}, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.PasswordReset{}, domain.ErrTokenUsed
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE password_resets SET used = 1 WHERE user_id = ?`, reset.UserId); err != nil {
+		return domain.PasswordReset{}, err
+	}
+
+	return reset, tx.Commit()
+}
+
+// accessTokenColumns is the column list scanAccessToken expects
+const accessTokenColumns = `id, user_id, name, scopes, hash, created_at`
+
+func (s *mySQLiteRepository) SaveAccessToken(ctx context.Context, token domain.AccessToken) error {
+	scopes := make([]string, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO access_tokens (`+accessTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		token.Id, token.UserId, token.Name, strings.Join(scopes, " "), token.Hash, toUnixNano(token.CreatedAt),
+	)
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintPrimaryKey) || isUniqueViolationOn(err, "access_tokens.hash") {
+		return domain.ErrTokenExists
+	}
+
+	return err
+}
+
+func (s *mySQLiteRepository) GetAccessTokenByHash(ctx context.Context, hash string) (domain.AccessToken, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+accessTokenColumns+` FROM access_tokens WHERE hash = ?`, hash)
+	token, err := scanAccessToken(row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, domain.ErrTokenNotFound
+	}
+
+	return token, err
+}
+
+func (s *mySQLiteRepository) ListAccessTokens(ctx context.Context, userId int) ([]domain.AccessToken, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+accessTokenColumns+` FROM access_tokens WHERE user_id = ? ORDER BY created_at, id`, userId,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := make([]domain.AccessToken, 0)
+
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (s *mySQLiteRepository) DeleteAccessToken(ctx context.Context, userId int, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM access_tokens WHERE id = ? AND user_id = ?`, id, userId)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// scanAccessToken reads one row selected with accessTokenColumns
+func scanAccessToken(row interface{ Scan(dest ...any) error }) (domain.AccessToken, error) {
+	token := domain.AccessToken{}
+	var scopes string
+	var createdAt int64
+
+	err := row.Scan(&token.Id, &token.UserId, &token.Name, &scopes, &token.Hash, &createdAt)
+
+	for _, scope := range strings.Fields(scopes) {
+		token.Scopes = append(token.Scopes, domain.Scope(scope))
+	}
+	token.CreatedAt = fromUnixNano(createdAt)
+
+	return token, err
+}
+
+func (s *mySQLiteRepository) Save(ctx context.Context, user domain.User) (domain.User, error) {
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	// users without a handle store NULL, which the unique index lets repeat
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO users (email, hashed_password, is_chirpy_red, handle, display_name, bio, location, role, suspended, created_at, updated_at)
+		 VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)`,
+		user.Email, user.HashedPassword, user.IsChirpyRed, user.Handle, user.DisplayName, user.Bio, user.Location,
+		user.Role, user.Suspended, toUnixNano(user.CreatedAt), toUnixNano(user.UpdatedAt),
+	)
+
+	if isUniqueViolationOn(err, "users.handle") {
+		return user, domain.ErrHandleTaken
+	}
+
+	if isUniqueViolation(err) {
+		return user, domain.ErrEmailTaken
+	}
+
+	if err != nil {
+		return user, err
+	}
+
+	userId, err := result.LastInsertId()
+
+	if err != nil {
+		return user, err
+	}
+
+	user.ID = int(userId)
+	return user, nil
+}
+
+func (s *mySQLiteRepository) UpdateUserMembership(ctx context.Context, id int, isMember bool) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET is_chirpy_red = ? WHERE id = ?`, isMember, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) UpdateUserRole(ctx context.Context, id int, role domain.Role) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) UpdateUserSuspension(ctx context.Context, id int, suspended bool) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET suspended = ? WHERE id = ?`, suspended, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) UpdateUser(ctx context.Context, id int, user domain.User) error {
+	// an empty email or password hash keeps the stored one, mirroring the in-memory adapter
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE users
+		    SET email = CASE WHEN ? = '' THEN email ELSE ? END,
+		        hashed_password = CASE WHEN length(?) > 0 THEN ? ELSE hashed_password END,
+		        updated_at = ?
+		  WHERE id = ?`,
+		user.Email, user.Email, user.HashedPassword, user.HashedPassword, toUnixNano(user.UpdatedAt), id,
+	)
+
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) UpdateProfile(ctx context.Context, id int, user domain.User) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE users
+		    SET handle = NULLIF(?, ''),
+		        display_name = ?,
+		        bio = ?,
+		        location = ?,
+		        updated_at = ?
+		  WHERE id = ?`,
+		user.Handle, user.DisplayName, user.Bio, user.Location, toUnixNano(user.UpdatedAt), id,
+	)
+
+	if isUniqueViolation(err) {
+		return domain.ErrHandleTaken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) GetUserById(ctx context.Context, id int) (domain.User, error) {
+	user := domain.User{}
+	var handle sql.NullString
+	var createdAt, updatedAt int64
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, role, suspended, created_at, updated_at
+		   FROM users WHERE id = ?`, id,
+	).Scan(&user.ID, &user.Email, &user.HashedPassword, &user.IsChirpyRed,
+		&handle, &user.DisplayName, &user.Bio, &user.Location, &user.Role, &user.Suspended, &createdAt, &updatedAt)
+
+	user.Handle = handle.String
+	user.CreatedAt = fromUnixNano(createdAt)
+	user.UpdatedAt = fromUnixNano(updatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, domain.ErrUserNotFound
+	}
+
+	return user, err
+}
+
+func (s *mySQLiteRepository) GetUserId(ctx context.Context, emailid string) (int, error) {
+	var userId int
+
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, emailid).Scan(&userId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		// user not present
+		return 0, domain.ErrUserNotFound
+	}
+
+	return userId, err
+}
+
+func (s *mySQLiteRepository) GetUserIdByHandle(ctx context.Context, handle string) (int, error) {
+	var userId int
+
+	// the handle column compares without case
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE handle = ?`, handle).Scan(&userId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrUserNotFound
+	}
+
+	return userId, err
+}
+
+func (s *mySQLiteRepository) SaveTweet(ctx context.Context, tweet domain.Tweet) (domain.Tweet, error) {
+	if tweet.Kind == "" {
+		tweet.Kind = domain.TweetKindOriginal
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return tweet, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO tweets (body, author_id, kind, referenced_tweet_id, in_reply_to, conversation_id, hashtags, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tweet.Body, tweet.AuthorId, tweet.Kind, tweet.ReferencedTweetId, tweet.InReplyTo, tweet.ConversationId,
+		strings.Join(tweet.Hashtags, " "), toUnixNano(tweet.CreatedAt), toUnixNano(tweet.UpdatedAt),
+	)
+
+	if isUniqueViolation(err) {
+		return tweet, domain.ErrAlreadyRetweeted
+	}
+
+	if err != nil {
+		return tweet, err
+	}
+
+	tweetId, err := result.LastInsertId()
+
+	if err != nil {
+		return tweet, err
+	}
+
+	tweet.TweetId = int(tweetId)
+
+	if tweet.ConversationId == 0 {
+		// the tweet starts a conversation of its own
+		tweet.ConversationId = tweet.TweetId
+
+		if _, err := tx.ExecContext(ctx, `UPDATE tweets SET conversation_id = id WHERE id = ?`, tweetId); err != nil {
+			return tweet, err
+		}
+	}
+
+	for _, tag := range tweet.Hashtags {
+		_, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO hashtags (tag, tweet_id, created_at) VALUES (?, ?, ?)`,
+			tag, tweetId, toUnixNano(tweet.CreatedAt),
+		)
+
+		if err != nil {
+			return tweet, err
+		}
+	}
+
+	return tweet, tx.Commit()
+}
+
+func (s *mySQLiteRepository) DeleteTweet(ctx context.Context, tweet domain.Tweet) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM tweets WHERE id = ?`, tweet.TweetId)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// tweet not present
+		return domain.ErrTweetNotFound
+	}
+
+	// retweets go with the tweet, quotes stay
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM tweets WHERE kind = 'retweet' AND referenced_tweet_id = ?`, tweet.TweetId,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *mySQLiteRepository) GetTweetById(ctx context.Context, id int) (domain.Tweet, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tweetColumns+` FROM tweets WHERE id = ?`, id)
+
+	if err != nil {
+		return domain.Tweet{}, err
+	}
+
+	tweets, err := scanTweets(rows)
+
+	if err != nil {
+		return domain.Tweet{}, err
+	}
+
+	if len(tweets) == 0 {
+		return domain.Tweet{}, domain.ErrTweetNotFound
+	}
+
+	return tweets[0], nil
+}
+
+func (s *mySQLiteRepository) ListTweets(ctx context.Context, filter domain.TweetFilter, afterId int, limit int) ([]domain.Tweet, error) {
+	query := `SELECT ` + tweetColumns + ` FROM tweets WHERE 1 = 1`
+	args := []any{}
+
+	if filter.AuthorId != 0 {
+		query += ` AND author_id = ?`
+		args = append(args, filter.AuthorId)
+	}
+
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, toUnixNano(filter.Since))
+	}
+
+	if !filter.Until.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, toUnixNano(filter.Until))
+	}
+
+	order := `ASC`
+
+	if filter.Descending {
+		order = `DESC`
+		if afterId > 0 {
+			query += ` AND id < ?`
+			args = append(args, afterId)
+		}
+	} else {
+		query += ` AND id > ?`
+		args = append(args, afterId)
+	}
+
+	query += ` ORDER BY id ` + order + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweets(rows)
+}
+
+func (s *mySQLiteRepository) ListReplies(ctx context.Context, tweetId int, afterId int, limit int) ([]domain.Tweet, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+tweetColumns+` FROM tweets WHERE in_reply_to = ? AND id > ? ORDER BY id LIMIT ?`,
+		tweetId, afterId, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweets(rows)
+}
+
+func (s *mySQLiteRepository) CountReplies(ctx context.Context, tweetIds []int) (map[int]int, error) {
+	counts := make(map[int]int, len(tweetIds))
+
+	if len(tweetIds) == 0 {
+		return counts, nil
+	}
+
+	args := make([]any, 0, len(tweetIds))
+	placeholders := strings.Repeat(`?, `, len(tweetIds)-1) + `?`
+
+	for _, tweetId := range tweetIds {
+		counts[tweetId] = 0
+		args = append(args, tweetId)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT in_reply_to, COUNT(*) FROM tweets WHERE in_reply_to IN (`+placeholders+`) GROUP BY in_reply_to`,
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tweetId, count int
+		if err := rows.Scan(&tweetId, &count); err != nil {
+			return nil, err
+		}
+		counts[tweetId] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (s *mySQLiteRepository) ListTweetsByHashtag(ctx context.Context, tag string, beforeId int, limit int) ([]domain.Tweet, error) {
+	query := `SELECT ` + tweetColumns + ` FROM tweets WHERE id IN (SELECT tweet_id FROM hashtags WHERE tag = ?`
+	args := []any{tag}
+
+	if beforeId > 0 {
+		query += ` AND tweet_id < ?`
+		args = append(args, beforeId)
+	}
+
+	query += ` ORDER BY tweet_id DESC LIMIT ?) ORDER BY id DESC`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTweets(rows)
+}
+
+func (s *mySQLiteRepository) HashtagUses(ctx context.Context, since time.Time) ([]domain.HashtagUse, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tag, created_at FROM hashtags WHERE created_at >= ?`, toUnixNano(since))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	uses := make([]domain.HashtagUse, 0)
+
+	for rows.Next() {
+		var use domain.HashtagUse
+		var createdAt int64
+		if err := rows.Scan(&use.Tag, &createdAt); err != nil {
+			return nil, err
+		}
+		use.CreatedAt = fromUnixNano(createdAt)
+		uses = append(uses, use)
+	}
+
+	return uses, rows.Err()
+}
+
+func (s *mySQLiteRepository) Follow(ctx context.Context, followerId int, followeeId int) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)`, followerId, followeeId,
+	)
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintPrimaryKey) {
+		return domain.ErrAlreadyFollowing
+	}
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintForeignKey) {
+		return domain.ErrUserNotFound
+	}
+
+	return err
+}
+
+func (s *mySQLiteRepository) Unfollow(ctx context.Context, followerId int, followeeId int) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM follows WHERE follower_id = ? AND followee_id = ?`, followerId, followeeId,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrNotFollowing
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) ListFollowers(ctx context.Context, userId int, afterId int, limit int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT follower_id FROM follows WHERE followee_id = ? AND follower_id > ? ORDER BY follower_id LIMIT ?`,
+		userId, afterId, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+func (s *mySQLiteRepository) ListFollowing(ctx context.Context, userId int, afterId int, limit int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT followee_id FROM follows WHERE follower_id = ? AND followee_id > ? ORDER BY followee_id LIMIT ?`,
+		userId, afterId, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+func (s *mySQLiteRepository) CountFollows(ctx context.Context, userId int) (domain.FollowStats, error) {
+	stats := domain.FollowStats{}
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM follows WHERE followee_id = ?),
+		        (SELECT COUNT(*) FROM follows WHERE follower_id = ?)`,
+		userId, userId,
+	).Scan(&stats.Followers, &stats.Following)
+
+	return stats, err
+}
+
+func (s *mySQLiteRepository) LikeTweet(ctx context.Context, userId int, tweetId int) error {
+	// selecting from tweets inserts nothing when the tweet is unknown
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO likes (user_id, tweet_id) SELECT ?, id FROM tweets WHERE id = ?`, userId, tweetId,
+	)
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintPrimaryKey) {
+		return domain.ErrAlreadyLiked
+	}
+
+	if isConstraintViolation(err, sqlite3.ErrConstraintForeignKey) {
+		return domain.ErrUserNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return domain.ErrTweetNotFound
+	}
+
+	return nil
+}
+
+func (s *mySQLiteRepository) UnlikeTweet(ctx context.Context, userId int, tweetId int) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM likes WHERE user_id = ? AND tweet_id = ?`, userId, tweetId,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+
+	// tell an unknown tweet apart from a missing like
+	if _, err := s.GetTweetById(ctx, tweetId); err != nil {
+		return err
+	}
+
+	return domain.ErrNotLiked
+}
+
+func (s *mySQLiteRepository) ListTweetLikers(ctx context.Context, tweetId int, afterId int, limit int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT user_id FROM likes WHERE tweet_id = ? AND user_id > ? ORDER BY user_id LIMIT ?`,
+		tweetId, afterId, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+func (s *mySQLiteRepository) ListUserLikes(ctx context.Context, userId int, beforeId int, limit int) ([]int, error) {
+	query := `SELECT tweet_id FROM likes WHERE user_id = ?`
+	args := []any{userId}
+
+	if beforeId > 0 {
+		query += ` AND tweet_id < ?`
+		args = append(args, beforeId)
+	}
+
+	query += ` ORDER BY tweet_id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIds(rows)
+}
+
+func (s *mySQLiteRepository) LikeStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.LikeStats, error) {
+	stats := make(map[int]domain.LikeStats, len(tweetIds))
+
+	if len(tweetIds) == 0 {
+		return stats, nil
+	}
+
+	args := []any{viewerId}
+	placeholders := strings.Repeat(`?, `, len(tweetIds)-1) + `?`
+
+	for _, tweetId := range tweetIds {
+		stats[tweetId] = domain.LikeStats{}
+		args = append(args, tweetId)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tweet_id, COUNT(*), MAX(user_id = ?) FROM likes WHERE tweet_id IN (`+placeholders+`) GROUP BY tweet_id`,
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tweetId int
+		like := domain.LikeStats{}
+		if err := rows.Scan(&tweetId, &like.Count, &like.Liked); err != nil {
+			return nil, err
+		}
+		stats[tweetId] = like
+	}
+
+	return stats, rows.Err()
+}
+
+func (s *mySQLiteRepository) RetweetStats(ctx context.Context, viewerId int, tweetIds []int) (map[int]domain.RetweetStats, error) {
+	stats := make(map[int]domain.RetweetStats, len(tweetIds))
+
+	if len(tweetIds) == 0 {
+		return stats, nil
+	}
+
+	args := []any{viewerId}
+	placeholders := strings.Repeat(`?, `, len(tweetIds)-1) + `?`
+
+	for _, tweetId := range tweetIds {
+		stats[tweetId] = domain.RetweetStats{}
+		args = append(args, tweetId)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT referenced_tweet_id, COUNT(*), MAX(author_id = ?) FROM tweets
+		  WHERE kind = 'retweet' AND referenced_tweet_id IN (`+placeholders+`)
+		  GROUP BY referenced_tweet_id`,
+		args...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tweetId int
+		retweets := domain.RetweetStats{}
+		if err := rows.Scan(&tweetId, &retweets.Count, &retweets.Retweeted); err != nil {
+			return nil, err
+		}
+		stats[tweetId] = retweets
+	}
+
+	return stats, rows.Err()
+}
+
+func isUniqueViolation(err error) bool {
+	return isConstraintViolation(err, sqlite3.ErrConstraintUnique)
+}
+
+// isUniqueViolationOn tells apart tables with several unique columns, sqlite
+// names the failing column as table.column in the message
+func isUniqueViolationOn(err error, column string) bool {
+	return isUniqueViolation(err) && strings.Contains(err.Error(), column)
+}
+
+func isConstraintViolation(err error, code sqlite3.ErrNoExtended) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == code
+}
+
+func scanIds(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+
+	ids := make([]int, 0)
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// timestamps are stored as unix nanoseconds, 0 stands for the zero time
+func toUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos).UTC()
+}
+
+func scanTweets(rows *sql.Rows) ([]domain.Tweet, error) {
+	defer rows.Close()
+
+	tweets := make([]domain.Tweet, 0)
+
+	for rows.Next() {
+		tweet := domain.Tweet{}
+		var hashtags string
+		var createdAt, updatedAt int64
+		if err := rows.Scan(
+			&tweet.TweetId, &tweet.Body, &tweet.AuthorId, &tweet.Kind, &tweet.ReferencedTweetId,
+			&tweet.InReplyTo, &tweet.ConversationId, &hashtags, &createdAt, &updatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if hashtags != "" {
+			tweet.Hashtags = strings.Fields(hashtags)
+		}
+		tweet.CreatedAt = fromUnixNano(createdAt)
+		tweet.UpdatedAt = fromUnixNano(updatedAt)
+		tweets = append(tweets, tweet)
+	}
+
+	return tweets, rows.Err()
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anandh86/chirpy/internal/core/ports"
+)
+
+// In memory timeline cache
+func ProvideInMemoryTimelineCache() ports.ITimelineCache {
+	return &myInMemoryTimelineCache{
+		timelines: make(map[int][]int),
+		versions:  make(map[int]uint64),
+	}
+}
+
+// myInMemoryTimelineCache implements ports.ITimelineCache
+type myInMemoryTimelineCache struct {
+	mu        sync.RWMutex
+	timelines map[int][]int
+	// versions of the users' timelines, materialized or not
+	versions map[int]uint64
+}
+
+func (c *myInMemoryTimelineCache) Get(ctx context.Context, userId int) ([]int, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tweetIds, ok := c.timelines[userId]
+
+	if !ok {
+		return nil, c.versions[userId], false
+	}
+
+	return append([]int(nil), tweetIds...), c.versions[userId], true
+}
+
+func (c *myInMemoryTimelineCache) Set(ctx context.Context, userId int, tweetIds []int, version uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.versions[userId] != version {
+		return false
+	}
+
+	c.timelines[userId] = append([]int(nil), tweetIds...)
+	return true
+}
+
+func (c *myInMemoryTimelineCache) Push(ctx context.Context, userId int, tweetId int, maxLen int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions[userId]++
+
+	tweetIds, ok := c.timelines[userId]
+
+	if !ok {
+		// not materialized, it'll be built on the next read
+		return
+	}
+
+	tweetIds = insertSorted(tweetIds, tweetId)
+
+	if len(tweetIds) > maxLen {
+		tweetIds = append([]int(nil), tweetIds[len(tweetIds)-maxLen:]...)
+	}
+
+	c.timelines[userId] = tweetIds
+}
+
+func (c *myInMemoryTimelineCache) Invalidate(ctx context.Context, userId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions[userId]++
+	delete(c.timelines, userId)
+}
@@ -0,0 +1,58 @@
+package adapters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestInMemoryTimelineCache(t *testing.T) {
+	ctx := context.Background()
+	cache := ProvideInMemoryTimelineCache()
+
+	_, version, ok := cache.Get(ctx, 1)
+
+	if ok {
+		t.Fatal("expected no timeline before one is set")
+	}
+
+	// a push to a timeline that isn't materialized yet makes a rebuild that
+	// started before it stale
+	cache.Push(ctx, 1, 3, 3)
+
+	if cache.Set(ctx, 1, []int{1, 2}, version) {
+		t.Fatal("a rebuild older than a push was installed")
+	}
+
+	_, version, _ = cache.Get(ctx, 1)
+
+	if !cache.Set(ctx, 1, []int{1, 2, 3}, version) {
+		t.Fatal("an up to date rebuild was rejected")
+	}
+
+	cache.Push(ctx, 1, 5, 3)
+	cache.Push(ctx, 1, 4, 3)
+
+	if tweetIds, _, ok := cache.Get(ctx, 1); !ok || !reflect.DeepEqual(tweetIds, []int{3, 4, 5}) {
+		t.Fatalf("expected the newest 3 tweets in order, got %v", tweetIds)
+	}
+
+	// as does an invalidation
+	_, version, _ = cache.Get(ctx, 1)
+	cache.Invalidate(ctx, 1)
+
+	if cache.Set(ctx, 1, []int{3, 4, 5}, version) {
+		t.Fatal("a rebuild older than an invalidation was installed")
+	}
+
+	if _, _, ok := cache.Get(ctx, 1); ok {
+		t.Fatal("expected the timeline to be gone after an invalidation")
+	}
+
+	// other users' timelines have versions of their own
+	_, version, _ = cache.Get(ctx, 2)
+
+	if !cache.Set(ctx, 2, []int{1}, version) {
+		t.Fatal("another user's push made this rebuild stale")
+	}
+}
@@ -1,21 +1,74 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/anandh86/chirpy/internal/core/domain"
+	"github.com/anandh86/chirpy/internal/core/ports"
 	"github.com/anandh86/chirpy/internal/core/usecases"
 	"github.com/anandh86/chirpy/internal/handlers"
 	adapters "github.com/anandh86/chirpy/internal/repositories"
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/joho/godotenv"
 )
 
+// requestTimeout bounds how long a single request may spend in the core
+const requestTimeout = 30 * time.Second
+
 func main() {
 
+	// by default, godotenv will look for a file named .env in the current directory
+	godotenv.Load()
+
 	// wiring
-	userRepository := adapters.ProvideInMemoryRepo()
-	userUseCase := usecases.ProvideUserUseCase(userRepository)
-	userHttpHandler := handlers.ProvideUserHttpHandler(userUseCase)
+	userRepository, err := provideRepository()
+
+	if err != nil {
+		log.Fatalf("Couldn't set up the repository: %s", err)
+	}
+
+	timeline := provideTimelineStrategy(userRepository)
+	searchIndex := adapters.ProvideInMemorySearchIndex()
+
+	if err := usecases.RebuildSearchIndex(context.Background(), userRepository, searchIndex); err != nil {
+		log.Fatalf("Couldn't build the search index: %s", err)
+	}
+
+	clock := adapters.ProvideSystemClock()
+
+	// admins are only appointed from the configuration, they can't grant their own role
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		if err := usecases.PromoteAdmin(context.Background(), userRepository, clock, adminEmail); err != nil {
+			log.Printf("Couldn't make %s an admin: %s", adminEmail, err)
+		}
+	}
+
+	mailer, err := provideMailer()
+
+	if err != nil {
+		log.Fatalf("Couldn't set up the mailer: %s", err)
+	}
+
+	userUseCase := usecases.ProvideUserUseCase(
+		userRepository,
+		timeline,
+		clock,
+		adapters.ProvideRepositoryNotificationStore(userRepository),
+		searchIndex,
+		mailer,
+	)
+	keys, err := handlers.ProvideKeyManager(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_KEY_ID"), os.Getenv("JWT_SECRET"))
+
+	if err != nil {
+		log.Fatalf("Couldn't load the token signing keys: %s", err)
+	}
+
+	userHttpHandler := handlers.ProvideUserHttpHandler(userUseCase, keys)
 
 	const filepathRoot = "."
 	const port = "8080" // Set your desired port
@@ -25,33 +78,159 @@ func main() {
 	// Setup CORS middleware
 	r.Use(middlewareCors)
 
+	// Tag every request with an ID and a deadline, both travel down to the core in the request context
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Timeout(requestTimeout))
+
 	// Define routes
 	subRouter := chi.NewRouter()
 
-	subRouter.Post("/users", userHttpHandler.CreateUser)
-	subRouter.Put("/users", userHttpHandler.UpdateUser)
-	subRouter.Post("/login", userHttpHandler.LoginUser)
-	subRouter.Post("/refresh", userHttpHandler.Refresh)
-	subRouter.Post("/revoke", userHttpHandler.Revoke)
+	// Public endpoints, they authenticate with credentials of their own if any
+	subRouter.Group(func(public chi.Router) {
+		public.Post("/users", userHttpHandler.CreateUser)
+		public.Post("/login", userHttpHandler.LoginUser)
+		public.Post("/refresh", userHttpHandler.Refresh)
+		public.Post("/revoke", userHttpHandler.Revoke)
+		public.Post("/polka/webhooks", userHttpHandler.PolkaWebHooks)
+		public.Post("/password/forgot", userHttpHandler.ForgotPassword)
+		public.Post("/password/reset", userHttpHandler.ResetPassword)
+	})
+
+	// Endpoints anyone can read, tailored to the viewer when logged in
+	subRouter.Group(func(anyone chi.Router) {
+		anyone.Use(userHttpHandler.OptionalAuth)
+
+		anyone.Get("/users/{handle}", userHttpHandler.GetProfile)
+		anyone.Get("/users/{id}/followers", userHttpHandler.GetFollowers)
+		anyone.Get("/users/{id}/following", userHttpHandler.GetFollowing)
+		anyone.Get("/users/{id}/likes", userHttpHandler.GetUserLikes)
 
-	subRouter.Post("/tweets", userHttpHandler.PostTweet)
-	subRouter.Get("/tweets/{tweetId}", userHttpHandler.GetTweetById)
-	subRouter.Get("/tweets", userHttpHandler.GetAllTweets)
-	subRouter.Delete("/tweets/{tweetId}", userHttpHandler.DeleteTweet)
+		anyone.Get("/tweets/{tweetId}", userHttpHandler.GetTweetById)
+		anyone.Get("/tweets", userHttpHandler.GetAllTweets)
+		anyone.Get("/tweets/{tweetId}/likes", userHttpHandler.GetTweetLikers)
+		anyone.Get("/tweets/{tweetId}/thread", userHttpHandler.GetThread)
+
+		anyone.Get("/search/tweets", userHttpHandler.SearchTweets)
+		anyone.Get("/hashtags/{tag}/tweets", userHttpHandler.GetHashtagTweets)
+		anyone.Get("/trends", userHttpHandler.GetTrends)
+	})
+
+	// Endpoints that act on behalf of the holder of an access token, personal
+	// access tokens reach those their scopes cover
+	subRouter.Group(func(authenticated chi.Router) {
+		authenticated.Use(userHttpHandler.RequireAuth)
+
+		scoped := func(scope domain.Scope) chi.Router {
+			return authenticated.With(userHttpHandler.RequireScope(scope))
+		}
 
-	subRouter.Post("/polka/webhooks", userHttpHandler.PolkaWebHooks)
+		scoped(domain.ScopeProfileWrite).Put("/profile", userHttpHandler.UpdateProfile)
+
+		scoped(domain.ScopeFollowsWrite).Post("/users/{id}/follow", userHttpHandler.FollowUser)
+		scoped(domain.ScopeFollowsWrite).Delete("/users/{id}/follow", userHttpHandler.UnfollowUser)
+
+		scoped(domain.ScopeTweetsWrite).Post("/tweets", userHttpHandler.PostTweet)
+		scoped(domain.ScopeTweetsWrite).Delete("/tweets/{tweetId}", userHttpHandler.DeleteTweet)
+		scoped(domain.ScopeTweetsWrite).Post("/tweets/{tweetId}/like", userHttpHandler.LikeTweet)
+		scoped(domain.ScopeTweetsWrite).Delete("/tweets/{tweetId}/like", userHttpHandler.UnlikeTweet)
+
+		scoped(domain.ScopeTweetsRead).Get("/timeline/home", userHttpHandler.HomeTimeline)
+
+		scoped(domain.ScopeNotificationsRead).Get("/notifications", userHttpHandler.GetNotifications)
+		scoped(domain.ScopeNotificationsWrite).Post("/notifications/read", userHttpHandler.MarkNotificationsRead)
+
+		// the account itself is only managed from a login
+		authenticated.Group(func(account chi.Router) {
+			account.Use(userHttpHandler.RequireLogin)
+
+			account.Patch("/users", userHttpHandler.UpdateUser)
+
+			account.Get("/sessions", userHttpHandler.GetSessions)
+			account.Delete("/sessions/{id}", userHttpHandler.DeleteSession)
+			account.Post("/sessions/revoke-all", userHttpHandler.RevokeAllSessions)
+
+			account.Post("/tokens", userHttpHandler.CreateAccessToken)
+			account.Get("/tokens", userHttpHandler.GetAccessTokens)
+			account.Delete("/tokens/{id}", userHttpHandler.DeleteAccessToken)
+
+			// the policy in the core decides who may take these
+			account.Delete("/admin/tweets/{tweetId}", userHttpHandler.RemoveTweet)
+			account.Post("/admin/users/{id}/suspend", userHttpHandler.SuspendUser)
+			account.Post("/admin/users/{id}/unsuspend", userHttpHandler.UnsuspendUser)
+			account.Put("/admin/users/{id}/role", userHttpHandler.GrantRole)
+			account.Get("/admin/audit-log", userHttpHandler.GetAuditLog)
+		})
+	})
 
 	r.Mount("/api", subRouter)
 
+	r.Get("/.well-known/jwks.json", userHttpHandler.GetJWKS)
+
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
 	log.Fatal(http.ListenAndServe(":"+port, r))
 
 }
 
+// provideRepository picks the storage adapter from the REPOSITORY env variable,
+// falling back to the in-memory one when nothing is configured. The in-memory
+// one is made durable by pointing INMEMORY_DATA_DIR at a directory.
+func provideRepository() (ports.IRepository, error) {
+	switch os.Getenv("REPOSITORY") {
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "chirpy.db"
+		}
+		log.Printf("Using sqlite repository at %s\n", path)
+		return adapters.ProvideSQLiteRepo(path)
+	default:
+		dataDir := os.Getenv("INMEMORY_DATA_DIR")
+		if dataDir == "" {
+			log.Printf("Using in-memory repository\n")
+			return adapters.ProvideInMemoryRepo(), nil
+		}
+
+		snapshotInterval := 5 * time.Minute
+		if interval := os.Getenv("SNAPSHOT_INTERVAL"); interval != "" {
+			parsed, err := time.ParseDuration(interval)
+			if err != nil {
+				return nil, err
+			}
+			snapshotInterval = parsed
+		}
+		log.Printf("Using durable in-memory repository at %s\n", dataDir)
+		return adapters.ProvideDurableInMemoryRepo(dataDir, snapshotInterval)
+	}
+}
+
+// provideTimelineStrategy picks how home timelines are built from the
+// TIMELINE_STRATEGY env variable, fan-out-on-read unless told otherwise
+func provideTimelineStrategy(userRepository ports.IRepository) usecases.TimelineStrategy {
+	switch os.Getenv("TIMELINE_STRATEGY") {
+	case "fanout-write":
+		log.Printf("Building home timelines on write\n")
+		return usecases.ProvideFanOutOnWriteTimeline(userRepository, adapters.ProvideInMemoryTimelineCache())
+	default:
+		log.Printf("Building home timelines on read\n")
+		return usecases.ProvideFanOutOnReadTimeline(userRepository)
+	}
+}
+
+// provideMailer sets up email delivery. The outbox is the only adapter so far,
+// it writes every message to a file under MAIL_OUTBOX_DIR instead of sending it.
+func provideMailer() (ports.IMailer, error) {
+	dir := os.Getenv("MAIL_OUTBOX_DIR")
+	if dir == "" {
+		dir = "outbox"
+	}
+	log.Printf("Writing outgoing email to %s\n", dir)
+	return adapters.ProvideOutboxMailer(dir)
+}
+
 func middlewareCors(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)